@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// agentStartWait bounds how long a restarted agent gets to answer
+// "vpn status" before connecting is tried again
+const agentStartWait = 15 * time.Second
+
+// errAgentRestart is the failure of a client whose agent service is not
+// responding, typically after an OS update until the agent is restarted
+var errAgentRestart = knownFailure("the Cisco VPN agent service is not responding, which often happens " +
+	"after an OS update; connect again with --restart-agent to restart it, or restart it yourself " +
+	"(Linux: sudo systemctl restart vpnagentd; macOS: sudo launchctl kickstart -k " +
+	"system/com.cisco.secureclient.vpnagentd; Windows: restart the Cisco Secure Client agent service)")
+
+// agentService is how one OS manages the Cisco agent
+type agentService struct {
+	names   []string                // candidates, newest client first
+	exists  func(name string) bool  // whether this client's service is installed
+	restart func(name string) error // restarts it, asking for privileges if needed
+}
+
+// agentServices manage the agent on each supported OS
+var agentServices = map[string]agentService{
+	"linux": {
+		names: []string{"vpnagentd"},
+		exists: func(name string) bool {
+			return exec.Command("systemctl", "cat", name).Run() == nil
+		},
+		restart: func(name string) error {
+			return runPrivileged("systemctl", "restart", name)
+		},
+	},
+	"darwin": {
+		names: []string{"com.cisco.secureclient.vpnagentd", "com.cisco.anyconnect.vpnagentd"},
+		exists: func(name string) bool {
+			return exec.Command("launchctl", "print", "system/"+name).Run() == nil
+		},
+		restart: func(name string) error {
+			return runPrivileged("launchctl", "kickstart", "-k", "system/"+name)
+		},
+	},
+	"windows": {
+		names: []string{"csc_vpnagent", "vpnagent"},
+		exists: func(name string) bool {
+			return exec.Command("sc", "query", name).Run() == nil
+		},
+		restart: func(name string) error {
+			// Stopping fails if the agent already died; starting is what counts
+			if err := runPrivileged("net", "stop", name); err != nil {
+				slog.Debug("failed to stop the agent", "service", name, "error", err)
+			}
+			return runPrivileged("net", "start", name)
+		},
+	},
+}
+
+// runPrivileged runs a service command in view of the user, through sudo
+// when not already root. Windows has no sudo; there seccli must run as an
+// administrator.
+func runPrivileged(name string, args ...string) error {
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		args = append([]string{name}, args...)
+		name = "sudo"
+	}
+	slog.Info("running", "command", append([]string{name}, args...))
+	cmd := exec.CommandContext(commandCtx, name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// restartAgent restarts the Cisco agent service and waits for the client
+// to answer again, or for ctx to be cancelled
+func restartAgent(ctx context.Context, vpnExec string) error {
+	svc, ok := agentServices[runtime.GOOS]
+	if !ok {
+		return fmt.Errorf("don't know how to restart the agent on %s", runtime.GOOS)
+	}
+	name := ""
+	for _, candidate := range svc.names {
+		if svc.exists(candidate) {
+			name = candidate
+			break
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("no Cisco agent service found (looked for %v)", svc.names)
+	}
+	if err := svc.restart(name); err != nil {
+		return fmt.Errorf("failed to restart %s: %v", name, err)
+	}
+
+	deadline := time.Now().Add(agentStartWait)
+	for vpnStateCtx(ctx, vpnExec) == stateUnknown {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("restarted %s, but the client still isn't answering after %s", name, agentStartWait)
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	return nil
+}
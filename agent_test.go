@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRestartAgentStopsWhenCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses false as a client that never answers")
+	}
+	svc := agentServices[runtime.GOOS]
+	agentServices[runtime.GOOS] = agentService{
+		names:   []string{"vpnagentd"},
+		exists:  func(string) bool { return true },
+		restart: func(string) error { return nil },
+	}
+	defer func() { agentServices[runtime.GOOS] = svc }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	// false fails "vpn status", like an agent that never comes back
+	err := restartAgent(ctx, "false")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("restartAgent = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("restartAgent took %s to notice the cancellation", elapsed)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// testingEnv must be set for --assume-connected and --assume-disconnected
+// to take effect, so that they can't slip into real use
+const testingEnv = "SECCLI_TESTING"
+
+// assumedState is the state --assume-connected or --assume-disconnected
+// fixes, reported instead of asking the client; nil otherwise
+var assumedState *connState
+
+// setAssumedState applies the test-only --assume-* flags
+func setAssumedState(connected, disconnected bool) error {
+	if !connected && !disconnected {
+		return nil
+	}
+	if connected && disconnected {
+		return fmt.Errorf("--assume-connected and --assume-disconnected cannot be combined")
+	}
+	flag, state := "--assume-connected", stateConnected
+	if disconnected {
+		flag, state = "--assume-disconnected", stateDisconnected
+	}
+	if os.Getenv(testingEnv) != "1" {
+		return fmt.Errorf("%s is only for testing scripts; set %s=1 to use it", flag, testingEnv)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s: the VPN state is not checked\n", flag)
+	slog.Debug("assuming VPN state", "connected", state == stateConnected)
+	assumedState = &state
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/briandowns/spinner"
+	"golang.org/x/term"
+)
+
+// bannerPrompt is how the client asks to accept the server's banner
+const bannerPrompt = "accept? [y/n]"
+
+// bannerText pulls the server's banner out of the client's output up to
+// the banner prompt: the lines after the last notice or answered prompt
+func bannerText(output string) string {
+	if i := strings.LastIndex(strings.ToLower(output), bannerPrompt); i >= 0 {
+		output = output[:i]
+	}
+	lines := strings.Split(strings.ReplaceAll(output, "\r", ""), "\n")
+	start := len(lines)
+	for start > 0 {
+		line := strings.ToLower(strings.TrimSpace(lines[start-1]))
+		if strings.HasPrefix(line, ">>") || strings.HasSuffix(line, "password:") || strings.HasSuffix(line, "answer:") || strings.HasPrefix(line, "vpn>") {
+			break
+		}
+		start--
+	}
+	return strings.Trim(strings.Join(lines[start:], "\n"), "\n ")
+}
+
+// answerBanner accepts the server's banner. --show-banner prints it first,
+// and --require-banner-ack also asks on the terminal, declining the
+// connect unless the user agrees.
+func answerBanner(opts connectOptions, banner string, s *spinner.Spinner) (string, error) {
+	if !opts.ShowBanner && !opts.RequireBannerAck {
+		return "y", nil
+	}
+	s.Stop()
+	// -vv already shows it as part of the client's output
+	if !opts.Verbose {
+		if banner == "" {
+			banner = "(the server sent an empty banner)"
+		}
+		fmt.Fprintf(os.Stderr, "\n%s\n\n", banner)
+	}
+	if !opts.RequireBannerAck {
+		return "y", nil
+	}
+
+	if opts.NoPrompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "n", knownFailure("--require-banner-ack needs a terminal to acknowledge the server's banner on")
+	}
+	ok, err := askYesNo("Accept this banner and connect? [y/N]: ")
+	if err != nil {
+		return "n", fmt.Errorf("failed to read answer: %v", err)
+	}
+	if !ok {
+		return "n", knownFailure("the server's banner was not accepted; connection declined")
+	}
+	return "y", nil
+}
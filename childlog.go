@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// redacted replaces secrets in the --verbose-log file
+const redacted = "********"
+
+// childLog receives the VPN client's output for --verbose-log; nil when the
+// flag isn't given
+var childLog *childLogWriter
+
+// childLogWriter copies the client's output to a file, masking secrets. It
+// holds back a partial line so that a secret split across writes is still
+// masked.
+type childLogWriter struct {
+	mu      sync.Mutex
+	f       *os.File
+	secrets [][]byte
+	partial []byte
+}
+
+// openChildLog starts appending the client's output to path
+func openChildLog(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open verbose log: %v", err)
+	}
+	childLog = &childLogWriter{f: f}
+	return nil
+}
+
+// Write logs complete lines and keeps the rest for later. Write errors are
+// only logged, so a full disk can't break the connect.
+func (w *childLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	if i := bytes.LastIndexByte(w.partial, '\n'); i >= 0 {
+		w.write(w.partial[:i+1])
+		w.partial = append([]byte(nil), w.partial[i+1:]...)
+	}
+	return len(p), nil
+}
+
+// write masks secrets in b and writes it out
+func (w *childLogWriter) write(b []byte) {
+	for _, secret := range w.secrets {
+		b = bytes.ReplaceAll(b, secret, []byte(redacted))
+	}
+	if _, err := w.f.Write(b); err != nil {
+		slog.Debug("failed to write verbose log", "error", err)
+	}
+}
+
+// flush writes out a held-back partial line
+func (w *childLogWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.write(append(w.partial, '\n'))
+		w.partial = nil
+	}
+}
+
+// withChildLog adds the --verbose-log file, if any, to the writer for a
+// client session, headed by a line naming it. The secrets, such as the
+// password, are masked in the file.
+func withChildLog(w io.Writer, session string, secrets ...string) io.Writer {
+	if childLog == nil {
+		return w
+	}
+	childLog.flush()
+	childLog.mu.Lock()
+	for _, secret := range secrets {
+		if secret != "" {
+			childLog.secrets = append(childLog.secrets, []byte(secret))
+		}
+	}
+	childLog.mu.Unlock()
+	fmt.Fprintf(childLog, "--- %s %s\n", time.Now().Format(time.RFC3339), session)
+	return io.MultiWriter(w, childLog)
+}
+
+// closeChildLog flushes and closes the --verbose-log file
+func closeChildLog() {
+	if childLog == nil {
+		return
+	}
+	childLog.flush()
+	childLog.f.Close()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ciscoProfile is the part of a Cisco client XML profile seccli reads:
+// the server list the client's own UI offers
+type ciscoProfile struct {
+	XMLName xml.Name         `xml:"AnyConnectProfile"`
+	Hosts   []ciscoHostEntry `xml:"ServerList>HostEntry"`
+}
+
+// ciscoHostEntry is one server of a Cisco profile
+type ciscoHostEntry struct {
+	Name    string   `xml:"HostName"`
+	Address string   `xml:"HostAddress"`
+	Group   string   `xml:"UserGroup"`
+	Backups []string `xml:"BackupServerList>HostAddress"`
+}
+
+// host returns the entry's address in the form "connect" takes, with the
+// group as a path
+func (e ciscoHostEntry) host() string {
+	return withGroup(e.Address, e.Group)
+}
+
+// backupHosts returns the entry's backup servers, with the same group
+func (e ciscoHostEntry) backupHosts() []string {
+	var hosts []string
+	for _, backup := range e.Backups {
+		if backup = strings.TrimSpace(backup); backup != "" {
+			hosts = append(hosts, withGroup(backup, e.Group))
+		}
+	}
+	return hosts
+}
+
+// withGroup appends a group path to a host address
+func withGroup(address, group string) string {
+	address, group = strings.TrimSpace(address), strings.Trim(strings.TrimSpace(group), "/")
+	if group == "" {
+		return address
+	}
+	return address + "/" + group
+}
+
+// loadCiscoProfile reads a Cisco client XML profile
+func loadCiscoProfile(path string) (ciscoProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ciscoProfile{}, fmt.Errorf("failed to read Cisco profile: %v", err)
+	}
+	var p ciscoProfile
+	if err := xml.Unmarshal(data, &p); err != nil {
+		return ciscoProfile{}, fmt.Errorf("failed to parse Cisco profile %s: %v", path, err)
+	}
+	if len(p.Hosts) == 0 {
+		return ciscoProfile{}, fmt.Errorf("Cisco profile %s has no servers in its ServerList", path)
+	}
+	return p, nil
+}
+
+// ciscoHostEntryFor picks a server from a Cisco profile by its display name
+// or address, or the first one if name is empty
+func ciscoHostEntryFor(path, name string) (ciscoHostEntry, error) {
+	p, err := loadCiscoProfile(path)
+	if err != nil {
+		return ciscoHostEntry{}, err
+	}
+	var entry *ciscoHostEntry
+	var names []string
+	for i, e := range p.Hosts {
+		if name == "" || strings.EqualFold(e.Name, name) || strings.EqualFold(e.Address, name) {
+			entry = &p.Hosts[i]
+			break
+		}
+		names = append(names, e.Name)
+	}
+	if entry == nil {
+		return ciscoHostEntry{}, fmt.Errorf("Cisco profile %s has no server %q (it has: %s)", path, name, strings.Join(names, ", "))
+	}
+	if strings.TrimSpace(entry.Address) == "" || strings.ContainsAny(entry.host(), " \t\r\n") {
+		return ciscoHostEntry{}, fmt.Errorf("Cisco profile %s has an invalid HostAddress %q", path, entry.Address)
+	}
+	return *entry, nil
+}
+
+// ciscoProfileGlobs are where the Cisco clients keep installed XML
+// profiles on each OS, newest client first
+var ciscoProfileGlobs = map[string][]string{
+	"linux": {
+		"/opt/cisco/secureclient/vpn/profile/*.xml",
+		"/opt/cisco/anyconnect/profile/*.xml",
+	},
+	"darwin": {
+		"/opt/cisco/secureclient/vpn/profile/*.xml",
+		"/opt/cisco/anyconnect/profile/*.xml",
+	},
+	"windows": {
+		`C:\ProgramData\Cisco\Cisco Secure Client\VPN\Profile\*.xml`,
+		`C:\ProgramData\Cisco\Cisco AnyConnect Secure Mobility Client\Profile\*.xml`,
+	},
+}
+
+// discoveredHost is a server found in an installed Cisco profile
+type discoveredHost struct {
+	path  string
+	entry ciscoHostEntry
+}
+
+// label names the server the way the client's UI does, with its address
+func (d discoveredHost) label() string {
+	if d.entry.Name == "" {
+		return d.entry.host()
+	}
+	return fmt.Sprintf("%s (%s)", d.entry.Name, d.entry.host())
+}
+
+// findInstalledHosts lists the servers of the Cisco profiles matching
+// globs, keeping those matching name by display name or address if given.
+// Unreadable profiles are skipped.
+func findInstalledHosts(globs []string, name string) []discoveredHost {
+	var found []discoveredHost
+	for _, glob := range globs {
+		matches, _ := filepath.Glob(glob)
+		for _, path := range matches {
+			p, err := loadCiscoProfile(path)
+			if err != nil {
+				slog.Debug("skipping Cisco profile", "path", path, "error", err)
+				continue
+			}
+			for _, e := range p.Hosts {
+				if strings.TrimSpace(e.Address) == "" {
+					continue
+				}
+				if name == "" || strings.EqualFold(e.Name, name) || strings.EqualFold(e.Address, name) {
+					found = append(found, discoveredHost{path: path, entry: e})
+				}
+			}
+		}
+	}
+	return found
+}
+
+// discoverCiscoHost picks a server from the installed Cisco profiles for
+// --host-from-profile-file. Several are offered as a menu on a terminal.
+func discoverCiscoHost(name string) (discoveredHost, error) {
+	globs := ciscoProfileGlobs[runtime.GOOS]
+	found := findInstalledHosts(globs, name)
+	switch {
+	case len(found) == 0 && name != "":
+		return discoveredHost{}, fmt.Errorf("no server %q in the installed Cisco profiles (%s)", name, strings.Join(globs, ", "))
+	case len(found) == 0:
+		return discoveredHost{}, fmt.Errorf("no installed Cisco profile with servers found in %s; pass --profile-file or --vpn-host", strings.Join(globs, ", "))
+	case len(found) == 1:
+		slog.Info("using the server of the installed Cisco profile", "server", found[0].label(), "path", found[0].path)
+		return found[0], nil
+	}
+
+	labels := make([]string, len(found))
+	for i, d := range found {
+		labels[i] = d.label()
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return discoveredHost{}, fmt.Errorf("the installed Cisco profiles have several servers (%s); pick one with --profile-host", strings.Join(labels, ", "))
+	}
+	fmt.Println("Servers in the installed Cisco profiles:")
+	for i, label := range labels {
+		fmt.Printf("  %d) %s\n", i+1, label)
+	}
+	for {
+		fmt.Printf("Server [1-%d]: ", len(found))
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && answer == "" {
+			return discoveredHost{}, fmt.Errorf("failed to read the server choice: %v", err)
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(answer)); err == nil && n >= 1 && n <= len(found) {
+			return found[n-1], nil
+		}
+		fmt.Fprintf(os.Stderr, "Please enter a number from 1 to %d\n", len(found))
+	}
+}
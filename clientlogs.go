@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// clientLogGlobs are where the Cisco clients write log files on each OS,
+// newest client first
+var clientLogGlobs = map[string][]string{
+	"linux": {
+		"/opt/cisco/secureclient/log/*.log",
+		"/opt/cisco/secureclient/*/log/*.log",
+		"/opt/cisco/anyconnect/log/*.log",
+		"/opt/cisco/anyconnect/*/log/*.log",
+	},
+	"darwin": {
+		"/opt/cisco/secureclient/log/*.log",
+		"/opt/cisco/secureclient/*/log/*.log",
+		"/opt/cisco/anyconnect/log/*.log",
+		"/Library/Logs/Cisco/*.log",
+	},
+	"windows": {
+		`C:\ProgramData\Cisco\Cisco Secure Client\*\Logs\*.log`,
+		`C:\ProgramData\Cisco\Cisco Secure Client\*\Logs\*.txt`,
+		`C:\ProgramData\Cisco\Cisco AnyConnect Secure Mobility Client\*\Logs\*.log`,
+		`C:\ProgramData\Cisco\Cisco AnyConnect Secure Mobility Client\*\Logs\*.txt`,
+	},
+}
+
+// clientLogTail bounds how far back from the end of a log file the last
+// lines are looked for
+const clientLogTail = 1 << 20
+
+// clientLogPoll is how often --follow checks the log file for new lines
+const clientLogPoll = 500 * time.Millisecond
+
+// logLevels are the --level values, least severe first
+var logLevels = []string{"debug", "info", "warning", "error"}
+
+// logLevelPatterns recognize a line's level, checked most severe first.
+// Lines matching none count as info.
+var logLevelPatterns = []struct {
+	level   int
+	pattern *regexp.Regexp
+}{
+	{3, regexp.MustCompile(`(?i)\b(err|error|crit|critical|fatal|alert|emerg)\b`)},
+	{2, regexp.MustCompile(`(?i)\b(warn|warning)\b`)},
+	{0, regexp.MustCompile(`(?i)\b(debug|trace)\b`)},
+}
+
+// parseLogLevel returns the index of a --level value in logLevels
+func parseLogLevel(value string) (int, error) {
+	for i, level := range logLevels {
+		if strings.EqualFold(value, level) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid --level %q; use %s", value, strings.Join(logLevels, ", "))
+}
+
+// lineLevel guesses the level of a log line from the words in it
+func lineLevel(line string) int {
+	for _, p := range logLevelPatterns {
+		if p.pattern.MatchString(line) {
+			return p.level
+		}
+	}
+	return 1
+}
+
+// findClientLog returns the most recently written log file matching globs
+func findClientLog(globs []string) (string, error) {
+	var newest string
+	var newestTime time.Time
+	for _, glob := range globs {
+		matches, _ := filepath.Glob(glob)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			slog.Debug("found client log", "path", path, "modified", info.ModTime())
+			if newest == "" || info.ModTime().After(newestTime) {
+				newest, newestTime = path, info.ModTime()
+			}
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no Cisco client log files found")
+	}
+	return newest, nil
+}
+
+// systemLogCommand returns the command that reads the client's entries from
+// the system log, for clients that keep no log files of their own, and the
+// level its output still needs filtering by
+func systemLogCommand(follow bool, lines int, minLevel int) (*exec.Cmd, int, bool) {
+	switch runtime.GOOS {
+	case "linux":
+		args := []string{"--no-pager", "-o", "short-iso", "-t", "vpnagentd", "-t", "vpnui", "-t", "acvpnui", "-n", fmt.Sprint(lines)}
+		// journalctl filters by priority itself, so -n counts matching lines
+		priorities := []string{"debug", "info", "warning", "err"}
+		args = append(args, "-p", priorities[minLevel])
+		if follow {
+			args = append(args, "-f")
+		}
+		return exec.CommandContext(commandCtx, "journalctl", args...), 0, true
+	case "darwin":
+		predicate := `process == "vpnagentd" OR process == "Cisco Secure Client" OR process == "Cisco AnyConnect Secure Mobility Client"`
+		if follow {
+			return exec.CommandContext(commandCtx, "log", "stream", "--style", "compact", "--predicate", predicate), minLevel, true
+		}
+		return exec.CommandContext(commandCtx, "log", "show", "--style", "compact", "--last", "1d", "--predicate", predicate), minLevel, true
+	}
+	return nil, 0, false
+}
+
+// logTail keeps the last n lines written to it that are at least minLevel
+type logTail struct {
+	n        int
+	minLevel int
+	lines    []string
+}
+
+// add keeps line if it is severe enough, dropping the oldest past n
+func (l *logTail) add(line string) {
+	if lineLevel(line) < l.minLevel {
+		return
+	}
+	l.lines = append(l.lines, line)
+	if len(l.lines) > l.n {
+		l.lines = l.lines[1:]
+	}
+}
+
+// printLogLines writes the lines of r that are at least minLevel to w as
+// they come
+func printLogLines(w io.Writer, r io.Reader, minLevel int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := scanner.Text(); lineLevel(line) >= minLevel {
+			fmt.Fprintln(w, line)
+		}
+	}
+	return scanner.Err()
+}
+
+// tailFile prints the last lines of a log file at least minLevel, and
+// returns the offset it read up to
+func tailFile(path string, lines, minLevel int) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read client log: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read client log: %v", err)
+	}
+
+	start := max(info.Size()-clientLogTail, 0)
+	data := make([]byte, info.Size()-start)
+	if _, err := f.ReadAt(data, start); err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read client log: %v", err)
+	}
+	// The first line is likely cut off when reading from mid-file
+	if start > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	last := &logTail{n: lines, minLevel: minLevel}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if line != "" {
+			last.add(line)
+		}
+	}
+	for _, line := range last.lines {
+		fmt.Println(line)
+	}
+	return info.Size(), nil
+}
+
+// followFile prints lines added to a log file after offset until ctx is done.
+// A file that shrinks was rotated or truncated, and is read from the start.
+func followFile(ctx context.Context, path string, offset int64, minLevel int) error {
+	var partial []byte
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(clientLogPoll):
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Mid-rotation the file may briefly be missing
+			slog.Debug("client log unavailable", "path", path, "error", err)
+			continue
+		}
+		if info.Size() < offset {
+			slog.Debug("client log was rotated", "path", path)
+			offset, partial = 0, nil
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			slog.Debug("client log unavailable", "path", path, "error", err)
+			continue
+		}
+		data := make([]byte, info.Size()-offset)
+		n, err := f.ReadAt(data, offset)
+		f.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read client log: %v", err)
+		}
+		offset += int64(n)
+
+		// Hold back a line the client is still writing
+		partial = append(partial, data[:n]...)
+		i := bytes.LastIndexByte(partial, '\n')
+		if i < 0 {
+			continue
+		}
+		if err := printLogLines(os.Stdout, bytes.NewReader(partial[:i+1]), minLevel); err != nil {
+			return err
+		}
+		partial = append([]byte(nil), partial[i+1:]...)
+	}
+}
+
+// tailSystemLog prints the client's entries from the system log
+func tailSystemLog(cmd *exec.Cmd, follow bool, lines, minLevel int) error {
+	slog.Debug("reading client log from system log", "command", cmd.Args)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("no Cisco client log files found, and failed to read the system log: %v", err)
+	}
+
+	if follow {
+		err = printLogLines(os.Stdout, stdout, minLevel)
+	} else {
+		last := &logTail{n: lines, minLevel: minLevel}
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			last.add(scanner.Text())
+		}
+		err = scanner.Err()
+		for _, line := range last.lines {
+			fmt.Println(line)
+		}
+	}
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil && commandCtx.Err() == nil {
+		err = fmt.Errorf("failed to read the system log: %v", waitErr)
+	}
+	return err
+}
+
+// clientLogsAction handles the client-logs command
+func clientLogsAction(ctx context.Context, cmd *cli.Command) error {
+	minLevel, err := parseLogLevel(cmd.String("level"))
+	if err != nil {
+		return err
+	}
+	lines := int(cmd.Int("lines"))
+	if lines <= 0 {
+		return fmt.Errorf("--lines must be at least 1")
+	}
+	follow := cmd.Bool("follow")
+
+	path := cmd.String("file")
+	if path == "" {
+		path, err = findClientLog(clientLogGlobs[runtime.GOOS])
+		if err != nil {
+			if logCmd, level, ok := systemLogCommand(follow, lines, minLevel); ok {
+				return tailSystemLog(logCmd, follow, lines, level)
+			}
+			return fmt.Errorf("%v in %s; pass --file with the log's location", err, strings.Join(clientLogGlobs[runtime.GOOS], ", "))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "==> %s <==\n", path)
+
+	offset, err := tailFile(path, lines, minLevel)
+	if err != nil || !follow {
+		return err
+	}
+	return followFile(ctx, path, offset, minLevel)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// clockCheckTimeout bounds the clock skew preflight
+const clockCheckTimeout = 5 * time.Second
+
+// clockReferenceURL returns the HTTPS URL whose Date header the clock is
+// compared against: the VPN gateway itself unless one is configured
+func clockReferenceURL(host, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	host, _, _ = strings.Cut(host, "/")
+	return "https://" + host + "/"
+}
+
+// measureClockSkew returns how far the local clock is ahead of the
+// reference server (negative if behind). The Date header has one-second
+// resolution, so the result is only good to about a second.
+func measureClockSkew(ctx context.Context, referenceURL string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, clockCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, referenceURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("%s sent no usable Date header", referenceURL)
+	}
+	// Assume the server stamped the response halfway through the round trip
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(date), nil
+}
+
+// checkClockSkew warns if the system clock is off by more than maxSkew,
+// since Duo and TLS then fail in confusing ways. The check never blocks a
+// connect: an unreachable reference is only logged.
+func checkClockSkew(ctx context.Context, referenceURL string, maxSkew time.Duration) {
+	skew, err := measureClockSkew(ctx, referenceURL)
+	if err != nil {
+		slog.Info("skipping clock skew check", "reference", referenceURL, "error", err)
+		return
+	}
+	slog.Info("measured clock skew", "reference", referenceURL, "skew", skew.Round(time.Second).String())
+
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs <= maxSkew {
+		return
+	}
+	direction := "ahead of"
+	if skew < 0 {
+		direction = "behind"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: the system clock is %s %s %s; Duo and certificate checks may fail until it is corrected (e.g. by enabling NTP)\n",
+		abs.Round(time.Second), direction, referenceURL)
+}
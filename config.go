@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// profile is a saved set of connection parameters. It never holds secrets.
+type profile struct {
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	Method   string `json:"method,omitempty"`
+
+	// FallbackHosts and FallbackMethods are tried in turn when connecting
+	// with Host and Method fails
+	FallbackHosts   []string `json:"fallback_hosts,omitempty"`
+	FallbackMethods []string `json:"fallback_methods,omitempty"`
+}
+
+// config is the on-disk seccli configuration
+type config struct {
+	Profiles map[string]profile `json:"profiles,omitempty"`
+
+	// StatusRules add or override regular expressions that extract
+	// fields from "vpn status" output
+	StatusRules map[string]string `json:"status_rules,omitempty"`
+
+	// VPNExec is the client executable, used when --vpn-exec isn't given
+	VPNExec string `json:"vpn_exec,omitempty"`
+	// VPNExecPaths are checked before the built-in install locations when
+	// auto-detecting the client
+	VPNExecPaths []string `json:"vpn_exec_paths,omitempty"`
+
+	// ConfirmDisconnect requires confirmation before disconnecting, for
+	// machines where the tunnel is shared
+	ConfirmDisconnect bool `json:"confirm_disconnect,omitempty"`
+
+	// OnsiteProbe is the internal-only host:port or DNS name that
+	// --skip-if-onsite checks
+	OnsiteProbe string `json:"onsite_probe,omitempty"`
+
+	// EgressProbeURL replaces the public-IP service --check-egress asks
+	EgressProbeURL string `json:"egress_probe_url,omitempty"`
+
+	// PortalProbeURL replaces the URL the captive portal check fetches; it
+	// must answer 204 No Content over plain HTTP
+	PortalProbeURL string `json:"portal_probe_url,omitempty"`
+
+	// MethodTimeouts replace the default connect timeout per kind of
+	// method ("push", "phone", "sms", "passcode", "none")
+	MethodTimeouts map[string]string `json:"method_timeouts,omitempty"`
+
+	// OSMethods are the default method per OS ("linux", "darwin",
+	// "windows"), for when neither a flag, URI, profile nor VPN_METHOD
+	// gives one
+	OSMethods map[string]string `json:"os_methods,omitempty"`
+
+	// OnConnect and OnDisconnect are shell commands run after a connect and
+	// before a disconnect; they are text/template strings over hookData
+	OnConnect    string `json:"on_connect,omitempty"`
+	OnDisconnect string `json:"on_disconnect,omitempty"`
+
+	// StateMarkers replace the words "vpn status" uses for each state, for
+	// localized or unusual client builds
+	StateMarkers *stateMarkers `json:"state_markers,omitempty"`
+}
+
+// stateMarkers are case-sensitive substrings of "vpn status" output; any
+// one of a state's markers matches, and connected is checked first
+type stateMarkers struct {
+	Connected    []string `json:"connected,omitempty"`
+	Disconnected []string `json:"disconnected,omitempty"`
+}
+
+// exportedProfile is the file format of profile export/import
+type exportedProfile struct {
+	Name string `json:"name"`
+	profile
+}
+
+// profileNamePattern restricts profile names to something safe in file names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// defaultConfigPath returns the per-user config file location
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "seccli.json"
+	}
+	return filepath.Join(dir, "seccli", "config.json")
+}
+
+// configCache holds the merged configs read this run, by system and user
+// path, as one command consults the config at several steps. saveConfig
+// drops them.
+var configCache = map[[2]string]*config{}
+
+// loadConfig reads the user's config file over the system-wide one (see
+// mergeConfigFields), returning an empty config if neither exists yet. The
+// result is shared, so callers must not change it.
+func loadConfig(path string) (*config, error) {
+	systemPath := systemConfigPath()
+	key := [2]string{systemPath, path}
+	if cfg, ok := configCache[key]; ok {
+		return cfg, nil
+	}
+
+	system, err := readConfigFields(systemPath)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := systemLocks(systemPath, system)
+	if err != nil {
+		return nil, err
+	}
+	user, err := readConfigFields(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	data, _ := json.Marshal(mergeConfigFields(system, user, locked, path))
+	if err := json.Unmarshal(data, cfg); err != nil {
+		// Name the file with the field of the wrong type
+		for _, file := range []struct {
+			path   string
+			fields map[string]json.RawMessage
+		}{{systemPath, system}, {path, user}} {
+			data, _ := json.Marshal(file.fields)
+			if err := json.Unmarshal(data, &config{}); err != nil {
+				return nil, fmt.Errorf("failed to parse config %s: %v", file.path, err)
+			}
+		}
+		return nil, fmt.Errorf("failed to merge config %s into %s: %v", path, systemPath, err)
+	}
+	configCache[key] = cfg
+	return cfg, nil
+}
+
+// loadUserConfig reads one config file alone, returning an empty config if
+// it doesn't exist yet. Changes to the user's config start from it, so
+// that the system's settings aren't copied into it.
+func loadUserConfig(path string) (*config, error) {
+	cfg := &config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file, creating its directory if needed
+func saveConfig(path string, cfg *config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+	clear(configCache)
+	return nil
+}
+
+// validateProfile checks a profile's fields. Values end up as lines of the
+// "vpn -s" script, so line breaks would inject extra commands.
+func validateProfile(name string, p profile) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	if p.Host == "" {
+		return fmt.Errorf("profile %q has no host", name)
+	}
+	if !validHost(p.Host) {
+		return fmt.Errorf("profile %q has an invalid host %q", name, p.Host)
+	}
+	if strings.ContainsAny(p.Username, " \t\r\n") {
+		return fmt.Errorf("profile %q has an invalid username %q", name, p.Username)
+	}
+	if strings.ContainsAny(p.Method, "\r\n") {
+		return fmt.Errorf("profile %q has an invalid method %q", name, p.Method)
+	}
+	for _, host := range p.FallbackHosts {
+		if !validHost(host) {
+			return fmt.Errorf("profile %q has an invalid fallback host %q", name, host)
+		}
+	}
+	for _, method := range p.FallbackMethods {
+		if method == "" || strings.ContainsAny(method, "\r\n") {
+			return fmt.Errorf("profile %q has an invalid fallback method %q", name, method)
+		}
+	}
+	return nil
+}
+
+// validHost checks a profile host: an address, optionally followed by a
+// group path as in "vpn.example.edu/staff", but not a URL
+func validHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, " \t\r\n") &&
+		!strings.HasPrefix(host, "/") && !strings.Contains(host, "://")
+}
+
+// lookupProfile loads the config and returns the named profile
+func lookupProfile(configPath, name string) (profile, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return profile{}, err
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("no profile named %q in %s", name, configPath)
+	}
+	return p, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.json")
+	userPath := filepath.Join(dir, "user.json")
+	t.Setenv(systemConfigEnv, systemPath)
+	t.Cleanup(func() { clear(configCache) })
+
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		clear(configCache)
+	}
+
+	write(systemPath, `{"vpn_exec": "/opt/vpn", "profiles": {"org": {"host": "vpn.example.edu"}}}`)
+	write(userPath, `{"profiles": {"mine": {"host": "vpn2.example.edu"}}}`)
+	cfg, err := loadConfig(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.VPNExec != "/opt/vpn" || len(cfg.Profiles) != 2 {
+		t.Errorf("merged config = %+v", cfg)
+	}
+
+	// Later steps of the same run reuse it without reading the files
+	os.Remove(userPath)
+	if again, err := loadConfig(userPath); err != nil || again != cfg {
+		t.Errorf("second load = %p, %v; want the first result %p", again, err, cfg)
+	}
+	if err := saveConfig(userPath, &config{}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := loadConfig(userPath); err != nil || len(cfg.Profiles) != 1 {
+		t.Errorf("load after save = %+v, %v; want only the system profile", cfg, err)
+	}
+
+	for _, tt := range []struct{ path, data string }{
+		{systemPath, `{"vpn_exec": 4}`},
+		{userPath, `{"profiles": []}`},
+		{userPath, `{"profiles":`},
+	} {
+		write(systemPath, `{}`)
+		write(userPath, `{}`)
+		write(tt.path, tt.data)
+		_, err := loadConfig(userPath)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse config "+tt.path) {
+			t.Errorf("loading with %s in %s: error = %v, want it to name the file", tt.data, filepath.Base(tt.path), err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/briandowns/spinner"
+)
+
+// promptStep answers one prompt of the "vpn -s" connect flow
+type promptStep struct {
+	name    string
+	markers []string // lowercase prompt text; any one matches
+	secret  bool     // keep the answer out of debug logs
+	repeats int      // times the prompt may come back and be answered again
+
+	// answer returns the line to send, or ok=false to abort the connect
+	answer func() (line string, ok bool)
+}
+
+// endMarker is lowercase output that means the connect command is over,
+// after skipping the first skip occurrences
+type endMarker struct {
+	text string
+	skip int
+}
+
+// connectEndMarkers end the session: success, a failed login, an error, or
+// the client's command prompt coming back. The first "VPN>" is the prompt
+// the connect command itself is typed at.
+var connectEndMarkers = []endMarker{
+	{text: "state: connected"},
+	{text: "login failed"},
+	{text: ">> error:"},
+	{text: "vpn>", skip: 1},
+}
+
+// promptDriver is an io.Writer over the client's output that answers its
+// prompts on stdin as they appear, rather than piping a fixed script that
+// would answer whatever prompt happens to come next. Each prompt is only
+// answered once, or 1+repeats times; a further repeat (say, a re-prompt
+// after a wrong password) ends the session instead of looping.
+type promptDriver struct {
+	mu       sync.Mutex
+	stdin    io.WriteCloser
+	lines    chan stdinLine // answers waiting for writeStdin
+	steps    []promptStep
+	answered map[string]int
+	seen     map[string]int
+	pending  string // lowercased output not yet matched
+	closed   bool
+}
+
+// stdinLine is one line queued for the client
+type stdinLine struct {
+	text   string
+	secret bool
+}
+
+// newPromptDriver starts a session by sending command, then answers steps.
+// The driver must be closed once the client has exited.
+func newPromptDriver(stdin io.WriteCloser, command string, steps []promptStep) *promptDriver {
+	d := &promptDriver{
+		stdin:    stdin,
+		lines:    make(chan stdinLine, 16),
+		steps:    steps,
+		answered: map[string]int{},
+		seen:     map[string]int{},
+	}
+	go d.writeStdin()
+	d.send(command, false)
+	return d
+}
+
+// writeStdin writes queued lines to the client, then closes its stdin. It
+// runs apart from the output, so a client that stops reading can't stall
+// it. A client that exits early, say after rejecting the password, breaks
+// the pipe; the rest of the lines are dropped, and its exit status and
+// output report what went wrong.
+func (d *promptDriver) writeStdin() {
+	defer d.stdin.Close()
+	for line := range d.lines {
+		if _, err := io.WriteString(d.stdin, line.text+"\n"); err != nil {
+			slog.Debug("VPN client stopped reading input", "line", redactAnswer(line.text, line.secret), "error", err)
+			for range d.lines {
+			}
+			return
+		}
+	}
+}
+
+// Write scans the output for prompts and end markers
+func (d *promptDriver) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return len(p), nil
+	}
+	d.pending += strings.ToLower(string(p))
+
+	for !d.closed {
+		step, end, at, length := d.next()
+		if at < 0 {
+			break
+		}
+		d.pending = d.pending[at+length:]
+
+		if end != nil {
+			d.seen[end.text]++
+			if d.seen[end.text] > end.skip {
+				slog.Debug("connect session finished", "marker", end.text)
+				d.send("exit", false)
+				d.close()
+			}
+			continue
+		}
+
+		// A prompt may match several of its markers at once
+		for _, marker := range step.markers {
+			if i := strings.LastIndex(d.pending, marker); i >= 0 {
+				d.pending = d.pending[i+len(marker):]
+			}
+		}
+
+		if d.answered[step.name] > step.repeats {
+			slog.Debug("prompt repeated; ending session", "prompt", step.name)
+			d.close()
+			break
+		}
+		d.answered[step.name]++
+
+		line, ok := step.answer()
+		if !ok {
+			slog.Debug("not answering prompt; ending session", "prompt", step.name)
+			d.close()
+			break
+		}
+		slog.Debug("answering prompt", "prompt", step.name, "answer", redactAnswer(line, step.secret))
+		d.send(line, step.secret)
+	}
+
+	// Keep enough of the tail for a prompt split across writes
+	if len(d.pending) > 256 {
+		d.pending = d.pending[len(d.pending)-256:]
+	}
+	return len(p), nil
+}
+
+// next finds the earliest prompt or end marker in the pending output. It
+// returns at < 0 if there is none.
+func (d *promptDriver) next() (*promptStep, *endMarker, int, int) {
+	var step *promptStep
+	var end *endMarker
+	at, length := -1, 0
+	consider := func(marker string) bool {
+		i := strings.Index(d.pending, marker)
+		if i >= 0 && (at < 0 || i < at) {
+			at, length = i, len(marker)
+			return true
+		}
+		return false
+	}
+	for i := range d.steps {
+		for _, marker := range d.steps[i].markers {
+			if consider(marker) {
+				step, end = &d.steps[i], nil
+			}
+		}
+	}
+	for i := range connectEndMarkers {
+		if consider(connectEndMarkers[i].text) {
+			step, end = nil, &connectEndMarkers[i]
+		}
+	}
+	return step, end, at, length
+}
+
+// send queues one line for the client
+func (d *promptDriver) send(line string, secret bool) {
+	d.lines <- stdinLine{text: line, secret: secret}
+}
+
+// close ends the session: stdin is closed once the queued lines are out
+func (d *promptDriver) close() {
+	if !d.closed {
+		d.closed = true
+		close(d.lines)
+	}
+}
+
+// Close ends the session if the client exited without finishing it
+func (d *promptDriver) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.close()
+}
+
+// redactAnswer hides secret answers in logs
+func redactAnswer(line string, secret bool) string {
+	if secret {
+		return "********"
+	}
+	return line
+}
+
+// connectSteps are the prompts of a connect and how to answer them. An
+// empty method declines the Duo prompt, so --no-mfa fails fast instead of
+// waiting for a timeout. The spinner is stopped before asking anything on
+// the terminal, and says what Duo is doing once the method is sent. An
+// answer that turns the connect down records why in abortErr. The banner is
+// read back from output, which holds the client's output so far.
+func connectSteps(opts connectOptions, password string, abortErr *error, output *bytes.Buffer, s *spinner.Spinner) []promptStep {
+	fixed := func(line string) func() (string, bool) {
+		return func() (string, bool) { return line, true }
+	}
+	return []promptStep{
+		{name: "username", markers: []string{"username:"}, answer: fixed(opts.Username)},
+		// An expired password; detectConnectFailure explains it
+		{name: "new password", markers: []string{"new password:"}, answer: func() (string, bool) { return "", false }},
+		{name: "password", markers: []string{"password:"}, secret: true, answer: fixed(password)},
+		// Servers with a second authentication server ask for its username too
+		{name: "second username", markers: []string{"second username:"}, answer: fixed(opts.Username)},
+		secondFactorStep(opts, s),
+		{name: "untrusted certificate", markers: untrustedCertPrompts, answer: func() (string, bool) {
+			s.Stop()
+			if err := checkUntrustedCert(opts); err != nil {
+				*abortErr = err
+				return "n", true
+			}
+			return "y", true
+		}},
+		{name: "import certificate", markers: importCertPrompts, answer: fixed("n")},
+		{name: "banner", markers: []string{bannerPrompt}, answer: func() (string, bool) {
+			answer, err := answerBanner(opts, bannerText(output.String()), s)
+			if err != nil {
+				*abortErr = err
+			}
+			return answer, true
+		}},
+	}
+}
+
+// secondFactorStep answers the Duo prompt with the method. With a second
+// password, such as a PIN asked for after the AD password, the first
+// "Second Password:" gets that instead and the next one the method.
+func secondFactorStep(opts connectOptions, s *spinner.Spinner) promptStep {
+	sendMethod := func() (string, bool) {
+		s.Lock()
+		s.Suffix = opts.spinnerSuffix(duoWaitFor(opts.Method, nil).message)
+		s.Unlock()
+		return opts.Method, opts.Method != ""
+	}
+	step := promptStep{name: "second factor", markers: []string{"second password:", "answer:"}, secret: isPasscode(opts.Method), answer: sendMethod}
+	if opts.SecondPassword == "" {
+		return step
+	}
+
+	sent := false
+	step.secret, step.repeats = true, 1
+	step.answer = func() (string, bool) {
+		if !sent {
+			sent = true
+			return opts.SecondPassword, true
+		}
+		return sendMethod()
+	}
+	return step
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/urfave/cli/v3"
+)
+
+// duoPromptMarkers appear once Duo asks for a passcode or option
+var duoPromptMarkers = []string{"passcode or option", "second password:"}
+
+// duoWait describes the wait after answering the Duo prompt
+type duoWait struct {
+	message string        // shown in the spinner
+	expired string        // explains a timeout
+	timeout time.Duration // default --timeout for the whole connect
+}
+
+// duoWaits are keyed by methodKind. A call takes longest to answer; a
+// passcode needs no action on the phone at all.
+var duoWaits = map[string]duoWait{
+	"push":     {"Waiting for Duo push approval", "the Duo push wasn't approved in time", 2 * time.Minute},
+	"phone":    {"Calling your phone", "the Duo call wasn't answered in time", 3 * time.Minute},
+	"sms":      {"Sending SMS passcodes", "the server didn't respond to the SMS request in time", time.Minute},
+	"passcode": {"Checking your passcode", "the passcode wasn't checked in time", time.Minute},
+	"none":     {"Connecting to VPN", "the server didn't finish the login in time", time.Minute},
+}
+
+// methodKind groups a --method value with its siblings: push2 is a push,
+// and every passcode is alike
+func methodKind(method string) string {
+	switch {
+	case method == "":
+		return "none"
+	case isPasscode(method):
+		return "passcode"
+	}
+	return strings.TrimRight(strings.ToLower(method), "0123456789")
+}
+
+// duoMethods are the named Duo methods, without a device number
+var duoMethods = []string{"push", "phone", "sms"}
+
+// methodSynonyms are other names people use for a Duo method
+var methodSynonyms = map[string]string{
+	"call": "phone",
+	"text": "sms",
+}
+
+// normalizeMethod lowercases and trims a method and maps synonyms, keeping
+// any device number: " Call2" becomes "phone2". Passcodes are kept as they
+// are, apart from surrounding spaces.
+func normalizeMethod(method string) string {
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "" || isPasscode(method) {
+		return method
+	}
+	base := strings.TrimRight(method, "0123456789")
+	if synonym, ok := methodSynonyms[base]; ok {
+		return synonym + method[len(base):]
+	}
+	return method
+}
+
+// duoWaitFor returns the wait for a method, with the default timeout
+// replaced by any in overrides (keyed by methodKind)
+func duoWaitFor(method string, overrides map[string]time.Duration) duoWait {
+	kind := methodKind(method)
+	wait, ok := duoWaits[kind]
+	if !ok {
+		wait = duoWait{"Waiting for Duo", "Duo didn't finish in time", 2 * time.Minute}
+	}
+	if d, ok := overrides[kind]; ok {
+		wait.timeout = d
+	}
+	return wait
+}
+
+// errDuoTimeout is a Duo push or call left unanswered until the connect
+// timeout, as opposed to a server that stopped responding
+var errDuoTimeout = errors.New("timed out waiting for Duo")
+
+// countsDown reports whether a method waits on the user, so the spinner
+// shows how long is left to answer
+func countsDown(method string) bool {
+	kind := methodKind(method)
+	return kind == "push" || kind == "phone"
+}
+
+// showCountdown keeps the spinner's message followed by the time left
+// until deadline, updated every second until ctx is done
+func showCountdown(ctx context.Context, s *spinner.Spinner, suffix func(string) string, message string, deadline time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		left := max(time.Until(deadline).Round(time.Second), 0)
+		s.Lock()
+		s.Suffix = suffix(fmt.Sprintf("%s, %d:%02d left", message, int(left.Minutes()), int(left.Seconds())%60))
+		s.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseMethodTimeouts reads the config's method_timeouts, e.g.
+// {"phone": "5m"}
+func parseMethodTimeouts(raw map[string]string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for kind, value := range raw {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid method_timeouts entry %q: %q", kind, value)
+		}
+		timeouts[strings.ToLower(kind)] = d
+	}
+	return timeouts, nil
+}
+
+// duoOptionHeaders are lowercase lines before the Duo options, most
+// specific first
+var duoOptionHeaders = []string{"select one of the following options", "duo two-factor login"}
+
+// duoOptionPattern matches lines like " 1. Duo Push to XXX-XXX-1234"
+var duoOptionPattern = regexp.MustCompile(`(?m)^\s*(\d+)\.\s+(.+?)\s*$`)
+
+// duoOption is one choice offered at the Duo prompt
+type duoOption struct {
+	Number      int    `json:"number"`
+	Description string `json:"description"`
+	Method      string `json:"method,omitempty"`
+}
+
+// duoDevices lists the Duo options the server offers
+type duoDevices struct {
+	Options []duoOption `json:"options"`
+}
+
+// String formats the options for text output
+func (d duoDevices) String() string {
+	var b strings.Builder
+	b.WriteString("Duo options offered by the server:")
+	for _, option := range d.Options {
+		fmt.Fprintf(&b, "\n  %d. %s", option.Number, option.Description)
+		if option.Method != "" {
+			fmt.Fprintf(&b, " (--method %s)", option.Method)
+		}
+	}
+	return b.String()
+}
+
+// parseDuoOptions extracts the numbered options from the Duo prompt text
+func parseDuoOptions(output string) []duoOption {
+	// Only look at the text after the last Duo header, so numbered lines in
+	// earlier notices aren't mistaken for options. Options mention Duo too
+	// ("Duo Push to ..."), so they can't be the anchor.
+	lower := strings.ToLower(output)
+	for _, header := range duoOptionHeaders {
+		if i := strings.LastIndex(lower, header); i >= 0 {
+			output = output[i+len(header):]
+			break
+		}
+	}
+
+	var options []duoOption
+	counts := map[string]int{}
+	for _, match := range duoOptionPattern.FindAllStringSubmatch(output, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		method := duoMethodFor(match[2])
+		if method != "" {
+			// Duo addresses the second phone as push2, phone2, ...
+			counts[method]++
+			if counts[method] > 1 {
+				method += strconv.Itoa(counts[method])
+			}
+		}
+		options = append(options, duoOption{Number: number, Description: match[2], Method: method})
+	}
+	return options
+}
+
+// duoMethodFor maps an option description to the method name Duo accepts
+func duoMethodFor(description string) string {
+	lower := strings.ToLower(description)
+	switch {
+	case strings.Contains(lower, "push"):
+		return "push"
+	case strings.Contains(lower, "phone call"), strings.Contains(lower, "call"):
+		return "phone"
+	case strings.Contains(lower, "sms"), strings.Contains(lower, "text"):
+		return "sms"
+	}
+	return ""
+}
+
+// listDuoDevices drives the client up to the Duo prompt, then aborts the
+// login and returns the offered options. Only opts' target, certificate and
+// banner settings apply; opts.Timeout limits the wait for the prompt.
+func listDuoDevices(ctx context.Context, vpnExec string, opts connectOptions) (duoDevices, error) {
+	s := newSpinner(" Checking VPN Status...")
+	s.Start()
+	defer s.Stop()
+
+	if vpnConnected(vpnExec) {
+		return duoDevices{}, fmt.Errorf("VPN is already connected; disconnect first")
+	}
+
+	s.Stop()
+
+	password, err := getPassword("Enter VPN password: ")
+	if err != nil {
+		return duoDevices{}, fmt.Errorf("failed to read password: %v", err)
+	}
+
+	s = newSpinner(" Waiting for the Duo prompt...")
+	s.Start()
+	defer s.Stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, vpnExec, "-s")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return duoDevices{}, fmt.Errorf("failed to set up VPN command: %v", err)
+	}
+	cmd.WaitDelay = time.Second
+
+	// The prompts are answered as for a connect, except that the Duo prompt
+	// ends the session, so the client never gets a second factor and the
+	// login can't complete
+	var abortErr error
+	var output bytes.Buffer
+	steps := connectSteps(opts, password, &abortErr, &output, s)
+	for i := range steps {
+		if steps[i].name == "second factor" {
+			steps[i].answer = func() (string, bool) { return "", false }
+		}
+	}
+	driver := newPromptDriver(stdin, "connect "+opts.Host, steps)
+	prompt := newPhaseWatcher(duoPromptMarkers)
+	writer := io.MultiWriter(&output, prompt, driver)
+	if verbosity >= verbosityChildOutput {
+		s.Stop()
+		writer = io.MultiWriter(os.Stdout, &output, prompt, driver)
+	}
+	writer = withChildLog(writer, "duo devices "+opts.Host, password)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	// Abort the partial login as soon as the options are on screen
+	go func() {
+		select {
+		case <-prompt.done:
+			slog.Debug("reached the Duo prompt; aborting login")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err = runTracked(cmd)
+	driver.Close()
+	s.Stop()
+	var launch *launchError
+	if errors.As(err, &launch) {
+		return duoDevices{}, err
+	}
+	if abortErr != nil {
+		return duoDevices{}, abortErr
+	}
+	if failure := detectConnectFailure(output.String()); failure != nil {
+		return duoDevices{}, failure
+	}
+	if !prompt.reached() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return duoDevices{}, fmt.Errorf("timed out after %s waiting for the Duo prompt; the client's last output was:\n%s",
+				opts.Timeout, indent(lastLines(output.String(), 5), "  "))
+		}
+		if err != nil {
+			return duoDevices{}, fmt.Errorf("VPN command failed: %v", err)
+		}
+		return duoDevices{}, fmt.Errorf("the server didn't show a Duo prompt (wrong password, or a profile without Duo?); the client's last output was:\n%s",
+			indent(lastLines(output.String(), 5), "  "))
+	}
+
+	// Make sure the aborted attempt left nothing behind
+	if vpnConnected(vpnExec) {
+		slog.Info("partial login left a session; disconnecting")
+		if err := disconnectVPN(vpnExec, false, false, nil); err != nil {
+			return duoDevices{}, fmt.Errorf("failed to abort partial session: %v", err)
+		}
+	}
+
+	options := parseDuoOptions(output.String())
+	if len(options) == 0 {
+		return duoDevices{}, fmt.Errorf("reached the Duo prompt but found no options to list")
+	}
+	return duoDevices{Options: options}, nil
+}
+
+// duoDevicesAction handles the duo devices command
+func duoDevicesAction(ctx context.Context, cmd *cli.Command) error {
+	host, username, _, err := resolveTarget(cmd)
+	if err != nil {
+		return err
+	}
+
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := cmd.String("trusted-fingerprint")
+	if fingerprint != "" {
+		if err := validateFingerprint(fingerprint); err != nil {
+			return err
+		}
+	}
+
+	devices, err := listDuoDevices(ctx, vpnExec, connectOptions{
+		Host:               host,
+		Username:           username,
+		Verbose:            verbosity >= verbosityChildOutput,
+		Timeout:            cmd.Duration("timeout"),
+		ShowBanner:         cmd.Bool("show-banner"),
+		TrustedFingerprint: fingerprint,
+	})
+	if err != nil {
+		return err
+	}
+	return render(devices, cmd.String("output"))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDuoOptions(t *testing.T) {
+	output := `  >> notice: 1. Please contact the help desk with questions.
+Duo two-factor login for me
+
+Enter a passcode or select one of the following options:
+
+ 1. Duo Push to iPhone (iOS)
+ 2. Duo Push to XXX-XXX-5678
+ 3. Phone call to XXX-XXX-1234
+ 4. Phone call to XXX-XXX-5678
+ 5. SMS passcodes to XXX-XXX-1234
+
+Second Password: `
+	want := []duoOption{
+		{Number: 1, Description: "Duo Push to iPhone (iOS)", Method: "push"},
+		{Number: 2, Description: "Duo Push to XXX-XXX-5678", Method: "push2"},
+		{Number: 3, Description: "Phone call to XXX-XXX-1234", Method: "phone"},
+		{Number: 4, Description: "Phone call to XXX-XXX-5678", Method: "phone2"},
+		{Number: 5, Description: "SMS passcodes to XXX-XXX-1234", Method: "sms"},
+	}
+	if got := parseDuoOptions(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDuoOptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNormalizeMethod(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"push", "push"},
+		{"PUSH", "push"},
+		{"  Push  ", "push"},
+		{"\tsms\n", "sms"},
+		{"push2", "push2"},
+		{"Phone2", "phone2"},
+		{" Call2", "phone2"},
+		{"call", "phone"},
+		{"TEXT", "sms"},
+		{"text3", "sms3"},
+		{"123456", "123456"},
+		{" 123456 ", "123456"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := normalizeMethod(tt.in); got != tt.want {
+			t.Errorf("normalizeMethod(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
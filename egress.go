@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultEgressProbeURL answers with the caller's public IP as plain text,
+// over both IPv4 and IPv6
+const defaultEgressProbeURL = "https://api64.ipify.org"
+
+// egressProbeTimeout bounds each egress lookup
+const egressProbeTimeout = 5 * time.Second
+
+// egressIPs is the public address seen by the probe over each IP family;
+// empty means that family had no route to it
+type egressIPs struct {
+	IPv4 string `json:"ipv4,omitempty"`
+	IPv6 string `json:"ipv6,omitempty"`
+}
+
+// egressReport compares the public addresses before and after connecting
+type egressReport struct {
+	Before egressIPs `json:"before"`
+	After  egressIPs `json:"after"`
+	Leaks  []string  `json:"leaks,omitempty"`
+}
+
+// lookupEgress asks the probe for our public address over IPv4 and IPv6
+func lookupEgress(ctx context.Context, probeURL string) egressIPs {
+	return egressIPs{
+		IPv4: lookupEgressIP(ctx, probeURL, "tcp4"),
+		IPv6: lookupEgressIP(ctx, probeURL, "tcp6"),
+	}
+}
+
+// lookupEgressIP fetches the probe over a single IP family
+func lookupEgressIP(ctx context.Context, probeURL, network string) string {
+	ctx, cancel := context.WithTimeout(ctx, egressProbeTimeout)
+	defer cancel()
+
+	// No proxy: the point is to see where our own traffic leaves from
+	var dialer net.Dialer
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		slog.Debug("bad egress probe URL", "url", probeURL, "error", err)
+		return ""
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Debug("egress probe failed", "network", network, "error", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		slog.Debug("egress probe failed", "network", network, "status", resp.StatusCode, "error", err)
+		return ""
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		slog.Debug("egress probe didn't return an IP address", "network", network)
+		return ""
+	}
+	return ip.String()
+}
+
+// compareEgress flags address families whose egress didn't move into the
+// tunnel
+func compareEgress(before, after egressIPs) egressReport {
+	report := egressReport{Before: before, After: after}
+	if after.IPv4 == "" {
+		report.Leaks = append(report.Leaks, "no IPv4 egress after connecting; the tunnel may not be carrying traffic")
+	} else if before.IPv4 != "" && after.IPv4 == before.IPv4 {
+		report.Leaks = append(report.Leaks, fmt.Sprintf("IPv4 egress is still %s; traffic isn't leaving through the VPN", after.IPv4))
+	}
+	if after.IPv6 != "" && after.IPv6 == before.IPv6 {
+		report.Leaks = append(report.Leaks, fmt.Sprintf("IPv6 egress is still %s; IPv6 traffic bypasses the tunnel", after.IPv6))
+	}
+	return report
+}
+
+// String formats the report for text output
+func (r egressReport) String() string {
+	orNone := func(v string) string {
+		if v == "" {
+			return "(none)"
+		}
+		return v
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Egress IPv4: %s -> %s\n", orNone(r.Before.IPv4), orNone(r.After.IPv4))
+	fmt.Fprintf(&b, "Egress IPv6: %s -> %s", orNone(r.Before.IPv6), orNone(r.After.IPv6))
+	for _, leak := range r.Leaks {
+		fmt.Fprintf(&b, "\nLeak: %s", leak)
+	}
+	return b.String()
+}
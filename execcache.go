@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// execCacheFile remembers where findVPNExec last found the client
+const execCacheFile = "vpn-exec.json"
+
+// execCache is a cached findVPNExec result. Size and ModTime identify the
+// installed build, so an upgrade in place also invalidates the entry.
+type execCache struct {
+	Client  string    `json:"client"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// cachedVPNExec returns the cached executable for client, or "" if there is
+// no entry or it no longer matches what's on disk
+func cachedVPNExec(client string) string {
+	path, err := statePath(execCacheFile)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var cached execCache
+	if err := json.Unmarshal(data, &cached); err != nil {
+		slog.Debug("ignoring unreadable exec cache", "path", path, "error", err)
+		return ""
+	}
+	if cached.Client != client {
+		return ""
+	}
+
+	info, err := os.Stat(cached.Path)
+	if err != nil || !isExecutable(cached.Path) {
+		slog.Debug("cached VPN executable is gone", "path", cached.Path)
+		return ""
+	}
+	if info.Size() != cached.Size || !info.ModTime().Equal(cached.ModTime) {
+		slog.Debug("cached VPN executable changed since it was found", "path", cached.Path)
+		return ""
+	}
+	slog.Debug("using cached VPN executable", "path", cached.Path)
+	return cached.Path
+}
+
+// saveVPNExec records a findVPNExec result. Failures are only logged, since
+// the cache is just an optimization.
+func saveVPNExec(client, vpnExec string) {
+	info, err := os.Stat(vpnExec)
+	if err != nil {
+		return
+	}
+	path, err := statePath(execCacheFile)
+	if err != nil {
+		slog.Debug("failed to cache VPN executable", "error", err)
+		return
+	}
+	data, _ := json.Marshal(execCache{Client: client, Path: vpnExec, Size: info.Size(), ModTime: info.ModTime()})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		slog.Debug("failed to cache VPN executable", "error", err)
+	}
+}
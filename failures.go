@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+)
+
+// connectFailure maps output the VPN client prints on a known failure to a
+// clearer error for the user
+type connectFailure struct {
+	patterns []string // lowercase substrings; any one matches
+	message  string
+}
+
+// connectFailures are checked in order against the client's output
+var connectFailures = []connectFailure{
+	{
+		patterns: []string{
+			"password has expired",
+			"password expired",
+			"password change required",
+			"password must be changed",
+			"new password:",
+		},
+		message: "your password has expired or must be changed; reset it through your " +
+			"institution's password portal (Cornell: https://netid.cornell.edu) and try again",
+	},
+	{
+		// The gateway caps concurrent sessions per user
+		patterns: []string{
+			"maximum number of simultaneous",
+			"simultaneous login",
+			"simultaneous-login",
+			"maximum number of sessions",
+			"session limit",
+		},
+		message: "maximum VPN sessions reached; disconnect another device (or run " +
+			"\"seccli disconnect --all\" there) and try again",
+	},
+	{
+		// Often seen after an OS update, until the agent is restarted
+		patterns: []string{
+			"vpn agent service is not responding",
+			"vpn service is not available",
+			"vpn service is unavailable",
+			"unable to communicate with the vpn agent",
+			"unable to create the interprocess communication depot",
+		},
+		message: string(errAgentRestart),
+	},
+	{
+		// Shared lab machines: the agent serves one user's session at a time
+		patterns: []string{
+			"another user is logged",
+			"another user has an active",
+			"multiple users are logged on",
+			"multiple users logged on",
+			"in use by another user",
+		},
+		message: string(errAnotherUser),
+	},
+	{
+		// The CLI can't run the browser-based SAML flow
+		patterns: []string{
+			"saml authentication",
+			"single sign-on",
+			"external browser",
+			"embedded browser",
+		},
+		message: "this VPN signs in through the browser (SAML/SSO), which the Cisco command-line client " +
+			"can't do; connect with the Cisco Secure Client app instead, or ask your IT department for " +
+			"a group that takes a password and Duo",
+	},
+}
+
+// errAnotherUser is the failure when another user of this machine holds the
+// VPN, as on shared lab machines
+var errAnotherUser = knownFailure("the VPN client refused because another user of this machine has a VPN " +
+	"session or is logged on; wait for them to finish or log out, or connect with --takeover to end their " +
+	"VPN session (they lose the tunnel)")
+
+// knownFailure is the error for a failure recognized in the client's
+// output. Retrying elsewhere won't help with these.
+type knownFailure string
+
+// Error returns the explanation for the user
+func (f knownFailure) Error() string {
+	return string(f)
+}
+
+// clientMessages returns the lowercased lines the client reports status
+// and errors with (">> ..." and "error: ..."), and the password change
+// prompt. Banners and echoed input, like the username, are left out, so
+// their wording can't pass for a failure. A message can follow the prompt
+// it answers on the same line, so ">>" counts anywhere in a line.
+func clientMessages(output string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.ToLower(output), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, ">>"); i >= 0 {
+			line = line[i:]
+		} else if !strings.HasPrefix(line, "error:") && !strings.HasPrefix(line, "new password:") {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// detectConnectFailure returns an error describing a known failure found in
+// the client's status and error lines, or nil if none matched
+func detectConnectFailure(output string) error {
+	lower := clientMessages(output)
+	for _, failure := range connectFailures {
+		for _, pattern := range failure.patterns {
+			if strings.Contains(lower, pattern) {
+				return knownFailure(failure.message)
+			}
+		}
+	}
+	return nil
+}
+
+// mfaPrompts are lowercase markers of a Duo/MFA prompt in the client's output
+var mfaPrompts = []string{"second password:", "duo two-factor", "passcode or option"}
+
+// promptedForMFA checks if the client asked for a second factor
+func promptedForMFA(output string) bool {
+	lower := strings.ToLower(output)
+	for _, prompt := range mfaPrompts {
+		if strings.Contains(lower, prompt) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLines returns the last n non-blank lines of output
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 0 {
+		return "(no output)"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indent prefixes every line of s
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
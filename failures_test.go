@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestDetectConnectFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   error
+	}{
+		{
+			name:   "browser sign-in",
+			output: "  >> error: The SAML authentication method requires an external browser, which is not supported by the CLI.\n  >> state: Disconnected\n",
+			want:   knownFailure(connectFailures[4].message),
+		},
+		{
+			name:   "session limit after the Duo prompt",
+			output: "Second Password:   >> Login denied. The maximum number of simultaneous logins for this user has been reached.\n  >> Login failed.\n",
+			want:   knownFailure(connectFailures[1].message),
+		},
+		{
+			name:   "expired password prompt",
+			output: "  >> Your password has expired.\nNew Password: ",
+			want:   knownFailure(connectFailures[0].message),
+		},
+		{
+			name:   "username echoed in the Duo prompt",
+			output: "Duo two-factor login for samlee\n\nEnter a passcode or select one of the following options:\n  >> state: Connected\n",
+		},
+		{
+			name:   "banner wording",
+			output: "Authorized use only. Exceeding the session limit or using single sign-on elsewhere is logged.\naccept? [y/n]: y\n  >> state: Connected\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectConnectFailure(tt.output); got != tt.want {
+				t.Errorf("detectConnectFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
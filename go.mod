@@ -3,15 +3,18 @@ module seccli
 go 1.24.4
 
 require (
-	github.com/briandowns/spinner v1.23.2 // indirect
+	github.com/briandowns/spinner v1.23.2
+	github.com/urfave/cli/v3 v3.4.1
+	golang.org/x/sys v0.36.0
+	golang.org/x/term v0.35.0
+)
+
+require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
 	github.com/fatih/color v1.7.0 // indirect
 	github.com/mattn/go-colorable v0.1.2 // indirect
 	github.com/mattn/go-isatty v0.0.8 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/urfave/cli/v2 v2.27.7 // indirect
-	github.com/urfave/cli/v3 v3.4.1 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
-	golang.org/x/sys v0.36.0 // indirect
-	golang.org/x/term v0.35.0 // indirect
 )
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// guiSettle is how long after a connect --no-gui keeps looking for a GUI
+// the connect brought up, since it can take a moment to appear
+const guiSettle = 2 * time.Second
+
+// clientGUI is how one OS finds and closes the Cisco client's GUI
+type clientGUI struct {
+	names   []string               // process names, newest client first
+	running func(name string) bool // whether the GUI is running
+	close   func(name string) error
+}
+
+// clientGUIs find and close the GUI on each supported OS
+var clientGUIs = map[string]clientGUI{
+	"linux": {
+		names: []string{"vpnui", "acvpnui"},
+		running: func(name string) bool {
+			return exec.Command("pgrep", "-x", name).Run() == nil
+		},
+		close: func(name string) error {
+			return exec.Command("pkill", "-x", name).Run()
+		},
+	},
+	"darwin": {
+		names: []string{"Cisco Secure Client", "Cisco AnyConnect Secure Mobility Client"},
+		running: func(name string) bool {
+			return exec.Command("pgrep", "-x", name).Run() == nil
+		},
+		close: func(name string) error {
+			// Quitting lets the app save its state, unlike a signal
+			return exec.Command("osascript", "-e", `quit app "`+name+`"`).Run()
+		},
+	},
+	"windows": {
+		names: []string{"csc_ui.exe", "vpnui.exe"},
+		running: func(name string) bool {
+			output, err := exec.Command("tasklist", "/FI", "IMAGENAME eq "+name, "/FO", "CSV", "/NH").Output()
+			return err == nil && strings.Contains(strings.ToLower(string(output)), strings.ToLower(name))
+		},
+		close: func(name string) error {
+			return exec.Command("taskkill", "/IM", name).Run()
+		},
+	},
+}
+
+// runningGUIs lists the client GUIs running now
+func runningGUIs() map[string]bool {
+	running := map[string]bool{}
+	gui, ok := clientGUIs[runtime.GOOS]
+	if !ok {
+		return running
+	}
+	for _, name := range gui.names {
+		if gui.running(name) {
+			running[name] = true
+		}
+	}
+	return running
+}
+
+// closeNewGUIs closes the client GUIs that weren't running before the
+// connect, for --no-gui. A GUI the user had open is left alone.
+func closeNewGUIs(before map[string]bool) {
+	gui, ok := clientGUIs[runtime.GOOS]
+	if !ok {
+		return
+	}
+	deadline := time.Now().Add(guiSettle)
+	for {
+		for name := range runningGUIs() {
+			if before[name] {
+				continue
+			}
+			slog.Info("closing the Cisco GUI the connect opened", "process", name)
+			if err := gui.close(name); err != nil {
+				slog.Debug("failed to close the Cisco GUI", "process", name, "error", err)
+			}
+			before[name] = true
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
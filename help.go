@@ -0,0 +1,199 @@
+package main
+
+// Help text for the commands. Each UsageText starts with the synopsis and
+// follows it with commented examples, so "seccli <command> --help" shows
+// ready-to-copy invocations.
+
+const connectDescription = `Connects to a Cisco Secure Client/AnyConnect VPN, answering the username,
+password and Duo prompts for you. The password is always read from the
+terminal and never stored. Connection parameters come from flags, a --uri,
+a saved --profile, or the Cisco client's own XML --profile-file (or the
+installed one, with --host-from-profile-file), in that order of precedence.`
+
+const connectUsageText = `seccli connect [options] [-- extra vpn arguments]
+
+# Connect, approving a Duo push on your phone
+seccli connect --username myNetID --vpn-host cuvpn.cuvpn.cornell.edu --method push
+
+# Connect with a one-time Duo passcode
+seccli connect -u myNetID -h cuvpn.cuvpn.cornell.edu --method 123456
+
+# Connect using a saved profile
+seccli connect --profile cornell
+
+# Save the host, username and method that worked as a profile
+seccli connect -u myNetID -h cuvpn.cuvpn.cornell.edu -m push --save-profile cornell
+
+# Reconnect: drop the current session, then connect again
+seccli disconnect && seccli connect --profile cornell
+
+# Connect as last time, asking only for the password
+seccli connect
+seccli connect --last --method sms
+
+# Check that your password still works without staying connected
+seccli connect --profile cornell --test
+
+# Pass arguments the wrapper doesn't model straight to "vpn -s"
+seccli connect --profile cornell -- -profile MyProfile`
+
+const disconnectDescription = `Disconnects the active VPN session. With --confirm-disconnect (or
+"confirm_disconnect" in the config) it shows the session and asks first.`
+
+const disconnectUsageText = `seccli disconnect [options]
+
+# Disconnect
+seccli disconnect
+
+# Ask before disconnecting a shared tunnel, or skip the question in scripts
+seccli disconnect --confirm-disconnect
+seccli disconnect --yes
+
+# Tear down every session, e.g. at logout; a no-op if none is active
+seccli disconnect --all`
+
+const statusDescription = `Reports whether the VPN is connected, plus any fields the status rules
+extract and the last connect failure.`
+
+const statusUsageText = `seccli status [options]
+
+# Show whether the VPN is up
+seccli status
+
+# Machine-readable status
+seccli --output json status
+
+# Keep a JSON snapshot for another process to poll, e.g. from cron
+seccli --output json status --output-file /var/tmp/vpn-status.json
+
+# In a shell prompt, reuse a result up to 2 seconds old
+seccli status --quiet --cache-ttl 2s`
+
+const promptDescription = `Prints a short indicator for a shell prompt: a lock when the VPN is up, and
+nothing otherwise. To keep the prompt fast it reuses a status from the last
+few seconds (shared with status --cache-ttl), trusts a visible Cisco tunnel
+interface, and gives up on a slow client after --timeout.`
+
+const promptUsageText = `seccli prompt [options]
+
+# bash: show the indicator before the working directory
+PS1='$(seccli prompt)\w \$ '
+
+# zsh
+setopt PROMPT_SUBST
+PROMPT='$(seccli prompt)%~ %# '
+
+# Custom symbols
+seccli prompt --connected-symbol '[VPN] ' --disconnected-symbol '[--] '`
+
+const statsDescription = `Shows details of the active tunnel as reported by "vpn stats": server,
+assigned address, tunnel mode, protocol, cipher and traffic counters.`
+
+const statsUsageText = `seccli stats [options]
+
+# Show the summary
+seccli stats
+
+# Include every field the client reports
+seccli stats --all`
+
+const monitorDescription = `Prints the tunnel's upload and download rates on a line per reading,
+worked out from the traffic counters of consecutive "vpn stats" runs. With
+--json (or --output json) each reading is one JSON object per line. Stops
+when the tunnel goes down.`
+
+const monitorUsageText = `seccli monitor [options]
+
+# Watch throughput until Ctrl-C
+seccli monitor
+
+# Read every 5 seconds, 12 times, as JSON lines
+seccli monitor --json --interval 5s --count 12`
+
+const historyDescription = `Lists the connects and disconnects seccli has made, from a log kept in the
+state directory. For a connect the duration is how long connecting took, and
+the method in parentheses the MFA method used; for a disconnect, the duration
+is how long the session had been up.`
+
+const historyUsageText = `seccli history [options]
+
+# Show every event
+seccli history
+
+# Last week's sessions as CSV, for a spreadsheet
+seccli history --csv --since 7d > vpn-history.csv`
+
+const clientLogsDescription = `Shows the end of the Cisco client's log, for problems seccli's own -v logs
+can't explain. It reads the most recently written log file in the client's
+install directory, or on Linux and macOS falls back to the client's entries in
+the system log (journalctl or log). --level guesses each line's level from
+words like "error" and "warning"; lines without one count as info.`
+
+const clientLogsUsageText = `seccli client-logs [options]
+
+# Show the last 50 lines
+seccli client-logs
+
+# Watch for errors while connecting in another terminal
+seccli client-logs --follow --level error`
+
+const profileExportUsageText = `seccli profile export <name> [options]
+
+# Print a profile
+seccli profile export cornell
+
+# Save it to share with others
+seccli profile export cornell --file cornell.json`
+
+const profileImportUsageText = `seccli profile import <file> [options]
+
+# Add a shared profile
+seccli profile import cornell.json
+
+# Add it under another name, or replace an existing one
+seccli profile import cornell.json --name work
+seccli profile import cornell.json --force`
+
+const profileValidateDescription = `Checks profiles without connecting, so a broken one doesn't cost a
+failed connect and a wasted Duo push: required fields, a known Duo method,
+hosts that resolve, and a VPN client that can be run. Prints PASS or FAIL
+per profile and exits non-zero if any failed.`
+
+const profileValidateUsageText = `seccli profile validate <name>... [options]
+
+# Check one profile
+seccli profile validate cornell
+
+# Check them all, e.g. after editing the config
+seccli profile validate --all
+seccli -o json profile validate --all`
+
+const configInitDescription = `Asks for the VPN host, your username and Duo method, looks for the VPN
+client, and saves them as a profile in the config file. Other settings in
+an existing config are kept. Every question can be answered with a flag
+instead, and --non-interactive asks nothing.`
+
+const configInitUsageText = `seccli config init [options]
+
+# Answer the questions
+seccli config init
+
+# Set up without prompts, e.g. from a provisioning script
+seccli config init --non-interactive --vpn-host cuvpn.cuvpn.cornell.edu --username myNetID --force`
+
+const uiDescription = `Opens a full-screen dashboard with the connection status, uptime and
+traffic counters. Keys: c connect, d disconnect, r reconnect, q quit.`
+
+const uiUsageText = `seccli ui [options]
+
+# Watch and control a saved profile
+seccli ui --profile cornell`
+
+const duoDevicesDescription = `Logs in up to the Duo prompt, lists the options the server offers with
+their --method values, then aborts the login. No push or call is sent.
+Certificate and banner prompts are answered as for connect.`
+
+const duoDevicesUsageText = `seccli duo devices [options]
+
+# List the options for a saved profile
+seccli duo devices --profile cornell`
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// historyFile logs connects and disconnects, one JSON event per line
+const historyFile = "history.jsonl"
+
+// historyEvent is one connect or disconnect. For a connect, Seconds is how
+// long connecting took and Method the MFA method used, as for
+// reportedMethod; for a disconnect, Seconds is how long the session had been
+// up.
+type historyEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Host    string    `json:"host,omitempty"`
+	Method  string    `json:"method,omitempty"`
+	Seconds float64   `json:"duration_seconds,omitempty"`
+}
+
+// historyLog is a list of events, printed one per line
+type historyLog []historyEvent
+
+// String formats the events for text output
+func (h historyLog) String() string {
+	if len(h) == 0 {
+		return "No connection history"
+	}
+	lines := make([]string, len(h))
+	for i, e := range h {
+		host := e.Host
+		if host == "" {
+			host = "(unknown host)"
+		}
+		line := fmt.Sprintf("%s  %-10s  %s", e.Time.Local().Format(time.DateTime), e.Event, host)
+		if e.Method != "" {
+			line += " (" + e.Method + ")"
+		}
+		if e.Seconds > 0 {
+			line += "  " + time.Duration(e.Seconds*float64(time.Second)).String()
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// recordEvent appends an event to the history. Failing to record it is only
+// logged, since the connect or disconnect itself went through.
+func recordEvent(e historyEvent) {
+	path, err := statePath(historyFile)
+	if err != nil {
+		slog.Debug("failed to record history", "error", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		slog.Debug("failed to record history", "error", err)
+		return
+	}
+	defer f.Close()
+	data, _ := json.Marshal(e)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		slog.Debug("failed to record history", "error", err)
+	}
+}
+
+// recordConnect logs a connect that took elapsed and used method
+func recordConnect(host, method string, elapsed time.Duration) {
+	recordEvent(historyEvent{Time: time.Now(), Event: "connect", Host: host, Method: method, Seconds: elapsed.Round(100 * time.Millisecond).Seconds()})
+}
+
+// recordDisconnect logs a disconnect. The host and session length come from
+// the last connect, if seccli made it.
+func recordDisconnect() {
+	e := historyEvent{Time: time.Now(), Event: "disconnect"}
+	if events, err := loadHistory(); err == nil && len(events) > 0 {
+		if last := events[len(events)-1]; last.Event == "connect" {
+			e.Host = last.Host
+			e.Seconds = e.Time.Sub(last.Time).Round(time.Second).Seconds()
+		}
+	}
+	recordEvent(e)
+}
+
+// loadHistory reads every recorded event, oldest first
+func loadHistory() ([]historyEvent, error) {
+	path, err := statePath(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer f.Close()
+
+	var events []historyEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e historyEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			slog.Debug("skipping unreadable history line", "path", path, "error", err)
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	return events, nil
+}
+
+// parseSince parses a --since value: a date, a date and time, or a duration
+// back from now such as "36h" or "7d"
+func parseSince(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q; use a date like 2026-01-31, or a duration like 36h or 7d", value)
+}
+
+// writeHistoryCSV writes events as CSV with a header row
+func writeHistoryCSV(events []historyEvent) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"time", "event", "host", "duration_seconds", "method"})
+	for _, e := range events {
+		seconds := ""
+		if e.Seconds > 0 {
+			seconds = strconv.FormatFloat(e.Seconds, 'f', 1, 64)
+		}
+		w.Write([]string{e.Time.Format(time.RFC3339), e.Event, e.Host, seconds, e.Method})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// historyAction handles the history command
+func historyAction(ctx context.Context, cmd *cli.Command) error {
+	events, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	if value := cmd.String("since"); value != "" {
+		since, err := parseSince(value, time.Now())
+		if err != nil {
+			return err
+		}
+		recent := events[:0]
+		for _, e := range events {
+			if !e.Time.Before(since) {
+				recent = append(recent, e)
+			}
+		}
+		events = recent
+	}
+
+	if cmd.Bool("csv") {
+		return writeHistoryCSV(events)
+	}
+	if events == nil {
+		events = []historyEvent{}
+	}
+	return render(historyLog(events), cmd.String("output"))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// hookTimeout bounds a hook command so a stuck one can't hang connect or
+// disconnect
+const hookTimeout = 30 * time.Second
+
+// hookData is what hook templates can refer to, e.g. {{.Interface}}. None
+// of it is secret: the method is reported the same way as in
+// "connect --output json".
+type hookData struct {
+	Event         string
+	Host          string
+	Server        string
+	ClientAddress string
+	Interface     string
+	Username      string
+	Method        string
+}
+
+// env exposes the data to the hook command as SECCLI_* variables
+func (d hookData) env() []string {
+	return []string{
+		"SECCLI_EVENT=" + d.Event,
+		"SECCLI_HOST=" + d.Host,
+		"SECCLI_SERVER=" + d.Server,
+		"SECCLI_CLIENT_ADDRESS=" + d.ClientAddress,
+		"SECCLI_INTERFACE=" + d.Interface,
+		"SECCLI_USERNAME=" + d.Username,
+		"SECCLI_METHOD=" + d.Method,
+	}
+}
+
+// connectHookData describes a session that just came up
+func connectHookData(result connectResult, username string) hookData {
+	return hookData{
+		Event:         "connect",
+		Host:          result.Host,
+		Server:        result.Server,
+		ClientAddress: result.ClientAddress,
+		Interface:     result.Interface,
+		Username:      username,
+		Method:        result.Method,
+	}
+}
+
+// disconnectHookData describes the session about to be torn down, as far
+// as "vpn stats" knows it
+func disconnectHookData(vpnExec string) hookData {
+	data := hookData{Event: "disconnect"}
+	stats, err := getStats(vpnExec)
+	if err != nil {
+		slog.Debug("could not read session details for hook", "error", err)
+		return data
+	}
+	data.Server = stats.Server
+	data.ClientAddress = stats.ClientAddress
+	data.Interface = interfaceWithAddr(stats.ClientAddress)
+	return data
+}
+
+// quoted returns the data with every value quoted for the shell, since
+// values such as the server come from the gateway and could otherwise
+// inject commands
+func (d hookData) quoted() hookData {
+	return hookData{
+		Event:         shellQuote(d.Event),
+		Host:          shellQuote(d.Host),
+		Server:        shellQuote(d.Server),
+		ClientAddress: shellQuote(d.ClientAddress),
+		Interface:     shellQuote(d.Interface),
+		Username:      shellQuote(d.Username),
+		Method:        shellQuote(d.Method),
+	}
+}
+
+// shellQuote makes value literal text for shellCommand's shell. cmd has no
+// quoting that stops % expansion, so there each special character is
+// escaped with ^ instead, and double quotes, which Go's own argument
+// quoting would mangle, are dropped.
+func shellQuote(value string) string {
+	if runtime.GOOS == "windows" {
+		var b strings.Builder
+		for _, c := range strings.ReplaceAll(value, `"`, "") {
+			if strings.ContainsRune("^&|<>()%!", c) {
+				b.WriteByte('^')
+			}
+			b.WriteRune(c)
+		}
+		return b.String()
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// renderHook substitutes the data, shell-quoted, into a hook command
+// template
+func renderHook(command string, data hookData) (string, error) {
+	tmpl, err := template.New(data.Event).Option("missingkey=error").Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("invalid on_%s hook: %v", data.Event, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data.quoted()); err != nil {
+		return "", fmt.Errorf("invalid on_%s hook: %v", data.Event, err)
+	}
+	return b.String(), nil
+}
+
+// runHook renders and runs a hook command through the shell. Hooks are
+// best effort: a failure is reported but doesn't undo the connect or stop
+// the disconnect. Their output goes to stderr so it can't corrupt
+// structured output.
+func runHook(ctx context.Context, command string, data hookData) {
+	if command == "" {
+		return
+	}
+	rendered, err := renderHook(command, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, rendered)
+	cmd.Env = append(os.Environ(), data.env()...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	slog.Info("running hook", "event", data.Event, "command", rendered)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: on_%s hook failed: %v\n", data.Event, err)
+	}
+}
+
+// shellCommand runs a command line through the platform's shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
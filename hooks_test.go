@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRenderHookQuotesValues(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs the hook through sh")
+	}
+	marker := filepath.Join(t.TempDir(), "injected")
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"plain", "vpn.example.edu"},
+		{"semicolon", "vpn.example.edu; touch " + marker},
+		{"command substitution", "$(touch " + marker + ")"},
+		{"backticks", "`touch " + marker + "`"},
+		{"single quote", "it's'; touch " + marker + "; '"},
+		{"variable", "$HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := renderHook("printf %s {{.Server}}", hookData{Event: "connect", Server: tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := shellCommand(context.Background(), rendered).Output()
+			if err != nil {
+				t.Fatalf("%s: %v", rendered, err)
+			}
+			if string(out) != tt.value {
+				t.Errorf("%s printed %q, want %q", rendered, out, tt.value)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatalf("%s ran an injected command", rendered)
+			}
+		})
+	}
+}
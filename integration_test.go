@@ -0,0 +1,379 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// The integration tests build seccli and the stub client once and drive
+// seccli through --vpn-exec, as a user would. They are skipped with -short.
+
+var (
+	buildOnce sync.Once
+	buildDir  string
+	buildErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if buildDir != "" {
+		os.RemoveAll(buildDir)
+	}
+	os.Exit(code)
+}
+
+// buildBinaries returns the paths of freshly built seccli and stub client
+func buildBinaries(tb testing.TB) (string, string) {
+	tb.Helper()
+	if testing.Short() {
+		tb.Skip("builds seccli and the stub client")
+	}
+	buildOnce.Do(func() {
+		buildDir, buildErr = os.MkdirTemp("", "seccli-test-")
+		if buildErr != nil {
+			return
+		}
+		for pkg, name := range map[string]string{".": "seccli", "./internal/stubvpn": "vpn"} {
+			out, err := exec.Command("go", "build", "-o", filepath.Join(buildDir, name), pkg).CombinedOutput()
+			if err != nil {
+				buildErr = fmt.Errorf("go build %s: %v\n%s", pkg, err, out)
+				return
+			}
+		}
+	})
+	if buildErr != nil {
+		tb.Fatal(buildErr)
+	}
+	return filepath.Join(buildDir, "seccli"), filepath.Join(buildDir, "vpn")
+}
+
+// stubEnv is a seccli environment with its own state, config and stub
+// client connection, plus any STUBVPN_* settings
+type stubEnv struct {
+	seccli, vpn string
+	vars        []string // the variables set on top of this process's
+	env         []string
+}
+
+func newStubEnv(tb testing.TB, settings ...string) *stubEnv {
+	tb.Helper()
+	seccli, vpn := buildBinaries(tb)
+	dir := tb.TempDir()
+	vars := append([]string{
+		"SECCLI_STATE_DIR=" + filepath.Join(dir, "state"),
+		"SECCLI_CONFIG=" + filepath.Join(dir, "config.json"),
+		systemConfigEnv + "=" + filepath.Join(dir, "system.json"),
+		"STUBVPN_STATE=" + filepath.Join(dir, "stub-state"),
+		"VPN_METHOD=",
+	}, settings...)
+	return &stubEnv{seccli: seccli, vpn: vpn, vars: vars, env: append(os.Environ(), vars...)}
+}
+
+// setenv moves this process into the environment, for tests that call
+// seccli's functions directly
+func (e *stubEnv) setenv(tb testing.TB) {
+	tb.Helper()
+	for _, v := range e.vars {
+		name, value, _ := strings.Cut(v, "=")
+		tb.Setenv(name, value)
+	}
+}
+
+// run runs seccli with args and the client's --vpn-exec, feeding stdin,
+// and returns its combined output and exit code
+func (e *stubEnv) run(tb testing.TB, stdin string, args ...string) (string, int) {
+	tb.Helper()
+	cmd := exec.Command(e.seccli, append(args, "--vpn-exec", e.vpn)...)
+	cmd.Env = e.env
+	cmd.Stdin = strings.NewReader(stdin)
+	return e.wait(tb, cmd)
+}
+
+// runOnTerminal is run with stdin on a pseudo-terminal, for commands that
+// only read the password from one
+func (e *stubEnv) runOnTerminal(tb testing.TB, input string, args ...string) (string, int) {
+	tb.Helper()
+	master, slave := openPTY(tb)
+	defer master.Close()
+	defer slave.Close()
+	if _, err := master.WriteString(input); err != nil {
+		tb.Fatal(err)
+	}
+	cmd := exec.Command(e.seccli, append(args, "--vpn-exec", e.vpn)...)
+	cmd.Env = e.env
+	cmd.Stdin = slave
+	return e.wait(tb, cmd)
+}
+
+func (e *stubEnv) wait(tb testing.TB, cmd *exec.Cmd) (string, int) {
+	tb.Helper()
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return out.String(), exitErr.ExitCode()
+	case err != nil:
+		tb.Fatalf("seccli %v: %v", cmd.Args[1:], err)
+	}
+	return out.String(), 0
+}
+
+// connect connects as "me", the password on stdin, with push unless args
+// say otherwise
+func (e *stubEnv) connect(tb testing.TB, password string, args ...string) (string, int) {
+	tb.Helper()
+	if len(args) == 0 {
+		args = []string{"-m", "push"}
+	}
+	args = append([]string{"connect", "-h", "vpn.example.edu", "-u", "me",
+		"--stdin-password", "--no-portal-check", "--timeout", "5s"}, args...)
+	return e.run(tb, password+"\n", args...)
+}
+
+// connected asks seccli status --quiet for the state
+func (e *stubEnv) connected(tb testing.TB) bool {
+	tb.Helper()
+	out, code := e.run(tb, "", "status", "--quiet")
+	switch code {
+	case 0:
+		return true
+	case exitDisconnected:
+		return false
+	}
+	tb.Fatalf("status --quiet exited %d:\n%s", code, out)
+	return false
+}
+
+// openPTY opens a pseudo-terminal pair
+func openPTY(tb testing.TB) (*os.File, *os.File) {
+	tb.Helper()
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		tb.Skipf("no pseudo-terminals: %v", err)
+	}
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		tb.Fatal(err)
+	}
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		tb.Fatal(err)
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		tb.Fatal(err)
+	}
+	return master, slave
+}
+
+func TestIntegrationConnectStatusDisconnect(t *testing.T) {
+	e := newStubEnv(t)
+
+	if e.connected(t) {
+		t.Fatal("connected before connecting")
+	}
+	out, code := e.connect(t, "password", "-m", "push", "--output", "json")
+	if code != 0 {
+		t.Fatalf("connect exited %d:\n%s", code, out)
+	}
+	var result connectResult
+	if err := json.Unmarshal([]byte(out[strings.Index(out, "{"):]), &result); err != nil {
+		t.Fatalf("connect output isn't JSON: %v\n%s", err, out)
+	}
+	if !result.Connected || result.Method != "push" || result.Transport != "dtls" {
+		t.Errorf("connect result = %+v", result)
+	}
+	if !e.connected(t) {
+		t.Fatal("not connected after connecting")
+	}
+
+	out, code = e.run(t, "", "status", "--output", "json")
+	if code != 0 {
+		t.Fatalf("status exited %d:\n%s", code, out)
+	}
+	var status vpnStatus
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf("status output isn't JSON: %v\n%s", err, out)
+	}
+	if !status.Connected || status.Lease == nil || status.Lease.Host != "vpn.example.edu" || status.LastError != nil {
+		t.Errorf("status = %+v", status)
+	}
+
+	if out, code := e.connect(t, "password"); code == 0 || !strings.Contains(out, "already connected") {
+		t.Errorf("second connect exited %d:\n%s", code, out)
+	}
+
+	if out, code := e.run(t, "", "disconnect", "--yes"); code != 0 {
+		t.Fatalf("disconnect exited %d:\n%s", code, out)
+	}
+	if e.connected(t) {
+		t.Fatal("connected after disconnecting")
+	}
+	if out, code := e.run(t, "", "disconnect", "--yes"); code == 0 || !strings.Contains(out, "not connected") {
+		t.Errorf("disconnect while disconnected exited %d:\n%s", code, out)
+	}
+}
+
+func TestIntegrationConnectFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []string
+		password string
+		args     []string // connect flags, -m push if none
+		want     string   // in the output
+	}{
+		{"wrong password", nil, "wrong", nil, "VPN connection failed"},
+		{"expired password", []string{"STUBVPN_EXPIRED=1"}, "password", nil, "your password has expired"},
+		{"session limit", []string{"STUBVPN_SESSION_LIMIT=1"}, "password", nil, "maximum VPN sessions reached"},
+		{"agent down", []string{"STUBVPN_AGENT_DOWN=1"}, "password", nil, "agent service is not responding"},
+		{"another user", []string{"STUBVPN_ANOTHER_USER=1"}, "password", nil, "another user of this machine"},
+		{"browser sign-in", []string{"STUBVPN_SSO=1"}, "password", nil, "signs in through the browser"},
+		{"unreachable host", []string{"STUBVPN_UNREACHABLE=vpn.example.edu"}, "password", nil, "VPN connection failed"},
+		{"client crash", []string{"STUBVPN_CRASH=1"}, "password", nil, "lost contact with the VPN agent process"},
+		{"rejected method", []string{"STUBVPN_METHODS=sms"}, "password", nil, "VPN connection failed"},
+		{"unexpected prompt", []string{"STUBVPN_HANG=1"}, "password", []string{"-m", "push", "--timeout", "2s"}, "timed out"},
+		{"untrusted certificate", []string{"STUBVPN_UNTRUSTED=1"}, "password", nil, "certificate is not trusted"},
+		{"MFA despite --no-mfa", nil, "password", []string{"--no-mfa"}, "--no-mfa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newStubEnv(t, tt.settings...)
+			out, code := e.connect(t, tt.password, tt.args...)
+			checkFailure(t, e, out, code, tt.want)
+		})
+	}
+}
+
+// checkFailure expects a failed connect that left the VPN down and
+// recorded the error for status
+func checkFailure(t *testing.T, e *stubEnv, out string, code int, want string) {
+	t.Helper()
+	if code == 0 {
+		t.Fatalf("connect succeeded:\n%s", out)
+	}
+	if !strings.Contains(strings.ToLower(out), strings.ToLower(want)) {
+		t.Errorf("connect output doesn't mention %q:\n%s", want, out)
+	}
+	if e.connected(t) {
+		t.Error("connected after a failed connect")
+	}
+	status, _ := e.run(t, "", "status")
+	if !strings.Contains(status, "Last error") {
+		t.Errorf("status doesn't show the last error:\n%s", status)
+	}
+}
+
+func TestIntegrationConnectSucceedsDespiteFailureWording(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []string
+		username string
+	}{
+		{"username containing saml", nil, "samlee"},
+		{"banner mentioning a session limit", []string{"STUBVPN_BANNER=Exceeding your session limit is logged. Single sign-on users: see the portal."}, "me"},
+		{"prompts on stderr", []string{"STUBVPN_STDERR=1"}, "me"},
+		{"no MFA asked", []string{"STUBVPN_NO_MFA=1"}, "me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newStubEnv(t, tt.settings...)
+			out, code := e.run(t, "password\n", "connect", "-h", "vpn.example.edu", "-u", tt.username, "-m", "push",
+				"--stdin-password", "--no-portal-check", "--timeout", "5s")
+			if code != 0 {
+				t.Fatalf("connect exited %d:\n%s", code, out)
+			}
+			if !e.connected(t) {
+				t.Fatal("not connected after connecting")
+			}
+			if status, _ := e.run(t, "", "status"); strings.Contains(status, "Last error") {
+				t.Errorf("status shows an error after a successful connect:\n%s", status)
+			}
+			e.run(t, "", "disconnect", "--yes")
+		})
+	}
+}
+
+func TestIntegrationDuoDevices(t *testing.T) {
+	e := newStubEnv(t, "STUBVPN_DEVICES=Duo Push to iPhone (iOS),Duo Push to XXX-XXX-5678,Phone call to XXX-XXX-1234,SMS passcodes to XXX-XXX-1234")
+	out, code := e.runOnTerminal(t, "password\n", "duo", "devices", "-h", "vpn.example.edu", "-u", "me", "--output", "json")
+	if code != 0 {
+		t.Fatalf("duo devices exited %d:\n%s", code, out)
+	}
+	var devices duoDevices
+	if err := json.Unmarshal([]byte(out[strings.Index(out, "{"):]), &devices); err != nil {
+		t.Fatalf("duo devices output isn't JSON: %v\n%s", err, out)
+	}
+	var methods []string
+	for _, option := range devices.Options {
+		methods = append(methods, option.Method)
+	}
+	if got, want := strings.Join(methods, ","), "push,push2,phone,sms"; got != want {
+		t.Errorf("methods = %s, want %s", got, want)
+	}
+	if e.connected(t) {
+		t.Error("duo devices connected")
+	}
+}
+
+func TestIntegrationDuoDevicesUntrustedCertificate(t *testing.T) {
+	const password = "correct-horse-battery"
+	e := newStubEnv(t, "STUBVPN_UNTRUSTED=1", "STUBVPN_PASSWORD="+password)
+	// -vv shows the client's output, where a misplaced answer would show
+	out, code := e.runOnTerminal(t, password+"\n", "-vv", "duo", "devices", "-h", "vpn.example.edu", "-u", "me")
+	if code == 0 {
+		t.Fatalf("duo devices succeeded despite the untrusted certificate:\n%s", out)
+	}
+	if !strings.Contains(out, "certificate is not trusted") {
+		t.Errorf("duo devices output doesn't explain the certificate:\n%s", out)
+	}
+	if strings.Contains(out, password) || strings.Contains(out, "unknown command") {
+		t.Errorf("duo devices sent the client an answer it didn't ask for:\n%s", out)
+	}
+}
+
+func TestIntegrationTimeoutAllKeepsTaggedOutput(t *testing.T) {
+	e := newStubEnv(t)
+	// Nothing answers the password prompt, so only the hard stop ends it
+	out, code := e.runOnTerminal(t, "", "--tag", "[vpn]", "--timeout-all", "500ms",
+		"connect", "-h", "vpn.example.edu", "-u", "me", "-m", "push", "--no-portal-check")
+	if code != exitTimeout {
+		t.Fatalf("connect exited %d, want %d:\n%s", code, exitTimeout, out)
+	}
+	if !strings.Contains(out, "[vpn] Error: gave up after --timeout-all 500ms") {
+		t.Errorf("tagged timeout error missing:\n%s", out)
+	}
+}
+
+func TestIntegrationStatusQuietKeepsTaggedLogs(t *testing.T) {
+	e := newStubEnv(t)
+	// A client that fails makes status --quiet log why and exit 2
+	cmd := exec.Command(e.seccli, "--tag", "[vpn]", "-vvv", "status", "--quiet", "--vpn-exec", "false")
+	cmd.Env = e.env
+	out, code := e.wait(t, cmd)
+	if code != exitUnknown {
+		t.Fatalf("status --quiet exited %d, want %d:\n%s", code, exitUnknown, out)
+	}
+	if !strings.Contains(out, "[vpn] ") || !strings.Contains(out, "VPN status command failed") {
+		t.Errorf("tagged log lines missing:\n%s", out)
+	}
+	if strings.Contains(out, "Error:") {
+		t.Errorf("status --quiet printed an error:\n%s", out)
+	}
+}
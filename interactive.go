@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// interactiveSettle is how long the client must stay quiet after printing
+// a partial line before --interactive-prompts takes it for a prompt
+const interactiveSettle = 200 * time.Millisecond
+
+// secretPrompts are lowercase words of a prompt whose answer isn't echoed
+var secretPrompts = []string{"password", "passcode", "pin:"}
+
+// interactiveDriver is the --interactive-prompts fallback for flows the
+// scripted prompts can't handle. The client's output is shown as is; once
+// it settles on a partial line, which is how the client prompts, the user
+// answers on the terminal and the line is passed on. It sends the connect
+// command and ends the session like a promptDriver without steps.
+type interactiveDriver struct {
+	session *promptDriver
+
+	mu     sync.Mutex
+	tail   string // the output's last, unfinished line
+	asking bool   // the user is answering a prompt
+	timer  *time.Timer
+
+	ended   chan struct{} // closed once the session is over
+	endOnce sync.Once
+}
+
+// terminalInput is where answers that are echoed are read from
+var terminalInput = bufio.NewReader(os.Stdin)
+
+// terminalRead is an answer being read from the terminal
+type terminalRead struct {
+	secret bool
+	done   chan struct{} // closed once line and err are set
+	line   string
+	err    error
+}
+
+// pendingRead is the terminal read in progress, if any. A read can't be
+// interrupted, so one whose session ended is handed to the next prompt of
+// the same kind rather than left blocked with a goroutine of its own.
+var pendingRead struct {
+	mu   sync.Mutex
+	read *terminalRead
+}
+
+// readAnswer returns the read of the next answer from the terminal
+func readAnswer(secret bool) *terminalRead {
+	pendingRead.mu.Lock()
+	defer pendingRead.mu.Unlock()
+	prev := pendingRead.read
+	if prev != nil && prev.secret == secret {
+		select {
+		case <-prev.done:
+			// The line answered a prompt that went away; don't reuse it
+		default:
+			return prev
+		}
+	}
+
+	r := &terminalRead{secret: secret, done: make(chan struct{})}
+	pendingRead.read = r
+	go func() {
+		defer close(r.done)
+		if prev != nil {
+			<-prev.done
+		}
+		if secret {
+			r.line, r.err = readPassword()
+			fmt.Fprintln(os.Stderr)
+			return
+		}
+		r.line, r.err = terminalInput.ReadString('\n')
+		if r.err != nil && r.line != "" {
+			r.err = nil
+		}
+	}()
+	return r
+}
+
+// taken marks r's answer as used
+func (r *terminalRead) taken() {
+	pendingRead.mu.Lock()
+	defer pendingRead.mu.Unlock()
+	if pendingRead.read == r {
+		pendingRead.read = nil
+	}
+}
+
+// newInteractiveDriver starts a session by sending command, then leaves
+// the prompts to the user. It must be closed once the client has exited.
+func newInteractiveDriver(stdin io.WriteCloser, command string) *interactiveDriver {
+	d := &interactiveDriver{
+		session: newPromptDriver(stdin, command, nil),
+		ended:   make(chan struct{}),
+	}
+	d.timer = time.AfterFunc(time.Hour, d.ask)
+	d.timer.Stop()
+	return d
+}
+
+// Write watches the output for a prompt, and the session for its end
+func (d *interactiveDriver) Write(p []byte) (int, error) {
+	d.session.Write(p)
+	if d.finished() {
+		d.end()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	text := strings.ReplaceAll(d.tail+string(p), "\r", "")
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	d.tail = text
+	d.timer.Reset(interactiveSettle)
+	return len(p), nil
+}
+
+// ask forwards the prompt the client settled on to the terminal. The
+// client's command prompt isn't one: the connect command is already sent,
+// and coming back to it ends the session.
+func (d *interactiveDriver) ask() {
+	d.mu.Lock()
+	prompt := strings.TrimSpace(d.tail)
+	lower := strings.ToLower(prompt)
+	if d.asking || prompt == "" || strings.HasPrefix(lower, "vpn>") || d.finished() {
+		d.mu.Unlock()
+		return
+	}
+	d.tail, d.asking = "", true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.asking = false
+		d.mu.Unlock()
+	}()
+
+	secret := containsAny(lower, secretPrompts)
+	slog.Debug("forwarding prompt to the terminal", "prompt", prompt, "secret", secret)
+	read := readAnswer(secret)
+	select {
+	case <-read.done:
+		read.taken()
+	case <-d.ended:
+		slog.Debug("session ended before the prompt was answered", "prompt", prompt)
+		return
+	}
+	if read.err != nil {
+		slog.Debug("failed to read answer; ending session", "prompt", prompt, "error", read.err)
+		d.session.Close()
+		d.end()
+		return
+	}
+	line := strings.TrimRight(read.line, "\r\n")
+
+	d.session.mu.Lock()
+	defer d.session.mu.Unlock()
+	if d.session.closed {
+		return
+	}
+	slog.Debug("answering prompt", "prompt", prompt, "answer", redactAnswer(line, secret))
+	d.session.send(line, secret)
+}
+
+// finished tells whether the session has ended
+func (d *interactiveDriver) finished() bool {
+	d.session.mu.Lock()
+	defer d.session.mu.Unlock()
+	return d.session.closed
+}
+
+// end stops waiting for an answer once the session is over
+func (d *interactiveDriver) end() {
+	d.endOnce.Do(func() { close(d.ended) })
+}
+
+// Close ends the session if the client exited without finishing it
+func (d *interactiveDriver) Close() {
+	d.mu.Lock()
+	d.timer.Stop()
+	d.mu.Unlock()
+	d.session.Close()
+	d.end()
+}
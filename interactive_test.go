@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clientStdin records what a driver sends the client
+type clientStdin struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *clientStdin) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+func (c *clientStdin) Close() error { return nil }
+
+func (c *clientStdin) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// TestInteractiveDriverEndedSessionsLeaveNoReaders ends sessions while
+// their prompt is still waiting for the user, which must not leave a
+// goroutine per session blocked on the terminal
+func TestInteractiveDriverEndedSessionsLeaveNoReaders(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	input := terminalInput
+	terminalInput = bufio.NewReader(r)
+	defer func() { terminalInput = input }()
+
+	prompted := func(stdin *clientStdin) *interactiveDriver {
+		d := newInteractiveDriver(stdin, "connect vpn.example.edu")
+		d.Write([]byte("Username: "))
+		time.Sleep(interactiveSettle + 50*time.Millisecond)
+		return d
+	}
+
+	prompted(&clientStdin{}).Close()
+	before := runtime.NumGoroutine()
+	for range 10 {
+		prompted(&clientStdin{}).Close()
+	}
+	if after := settledGoroutines(before); after > before {
+		t.Errorf("goroutines grew from %d to %d over 10 ended sessions", before, after)
+	}
+
+	// The read left over from the ended sessions answers the next prompt
+	stdin := &clientStdin{}
+	d := prompted(stdin)
+	defer d.Close()
+	if _, err := io.WriteString(w, "me\n"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); !strings.Contains(stdin.String(), "\nme\n"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("the answer wasn't sent; the client got %q", stdin.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// settledGoroutines gives goroutines that are finishing a moment to exit,
+// returning the count once it is at most want or two seconds have passed
+func settledGoroutines(want int) int {
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(2 * time.Second); n > want && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
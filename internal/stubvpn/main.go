@@ -0,0 +1,352 @@
+// Command stubvpn emulates the parts of the Cisco Secure Client "vpn" CLI
+// that seccli drives, so the real exec path can be exercised end to end
+// without a VPN:
+//
+//	go build -o /tmp/vpn ./internal/stubvpn
+//	seccli connect --username me --vpn-host example.edu --vpn-exec /tmp/vpn
+//
+// The connection state is kept in a file so that separate invocations agree.
+// Behaviour is controlled through environment variables:
+//
+//	STUBVPN_STATE     state file path (default: $TMPDIR/stubvpn-state)
+//	STUBVPN_PASSWORD  password to accept (default: "password")
+//	STUBVPN_METHODS   comma-separated Duo answers to accept (default: "push,sms,phone")
+//	STUBVPN_EXPIRED   if set, ask for a password change after the password
+//	STUBVPN_NO_MFA    if set, skip the Duo prompt
+//	STUBVPN_HANG      if set, hang at an unexpected "Group:" prompt
+//	STUBVPN_UNREACHABLE comma-separated hosts that can't be contacted
+//	STUBVPN_UNTRUSTED if set, warn about an untrusted server certificate
+//	STUBVPN_DUO_DELAY how long Duo takes to approve, e.g. "3s"
+//	STUBVPN_SESSION_LIMIT if set, refuse the login after Duo: too many sessions
+//	STUBVPN_AGENT_DOWN if set, fail every connect as if the agent had died
+//	STUBVPN_ANOTHER_USER if set, refuse to connect while another local user is logged on
+//	STUBVPN_SSO       if set, require browser-based SAML sign-in
+//	STUBVPN_SECOND_PASSWORD if set, ask for this second password before Duo
+//	STUBVPN_CRASH     if set, exit with an error right after the password
+//	STUBVPN_STDERR    if set, print everything, prompts included, to stderr
+//	STUBVPN_TRAFFIC   if set, grow the byte counters by the second while connected
+//	STUBVPN_BANNER    banner to show before connecting (default: "Authorized use only.")
+//	STUBVPN_DEVICES   comma-separated Duo options to offer (default: one push, phone and SMS device)
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: vpn -s | status | stats")
+		os.Exit(2)
+	}
+
+	// Some client versions prompt on stderr
+	if os.Getenv("STUBVPN_STDERR") != "" {
+		os.Stdout = os.Stderr
+	}
+
+	printBanner()
+
+	switch os.Args[1] {
+	case "-s":
+		runScript(bufio.NewScanner(os.Stdin))
+	case "status":
+		printState()
+	case "stats":
+		printStats()
+	default:
+		fmt.Printf("  >> error: unknown command %q\n", os.Args[1])
+		os.Exit(1)
+	}
+}
+
+// printBanner prints the header the real client shows on every invocation
+func printBanner() {
+	fmt.Println()
+	fmt.Println("Cisco Secure Client (version 5.1.0.0-stub) .")
+	fmt.Println()
+	fmt.Println("Copyright (c) 2004 - 2024 Cisco Systems, Inc.  All Rights Reserved.")
+	fmt.Println()
+	fmt.Println()
+}
+
+// runScript reads commands at the VPN> prompt the way "vpn -s" does
+func runScript(in *bufio.Scanner) {
+	printState()
+	for {
+		fmt.Print("VPN> ")
+		line, ok := readLine(in)
+		if !ok {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "connect":
+			if len(fields) < 2 {
+				fmt.Println("  >> error: connect requires a host")
+				continue
+			}
+			connect(in, fields[1])
+		case "disconnect":
+			setConnected("")
+			fmt.Println("  >> state: Disconnecting")
+			fmt.Println("  >> state: Disconnected")
+		case "state", "status":
+			printState()
+		case "exit", "quit":
+			fmt.Println("goodbye...")
+			return
+		default:
+			fmt.Printf("  >> error: unknown command %q\n", fields[0])
+		}
+	}
+}
+
+// connect walks through the username/password/Duo/banner prompts
+func connect(in *bufio.Scanner, host string) {
+	if host := connectedHost(); host != "" {
+		fmt.Printf("  >> error: already connected to %s\n", host)
+		return
+	}
+
+	if os.Getenv("STUBVPN_AGENT_DOWN") != "" {
+		fmt.Println("  >> error: Connection attempt has failed. The VPN agent service is not responding.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	if os.Getenv("STUBVPN_ANOTHER_USER") != "" {
+		fmt.Println("  >> error: The VPN connection is not available because multiple users are logged on to this computer.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	fmt.Printf("  >> contacting host (%s) for login information...\n", host)
+	fmt.Println("  >> notice: Contacting host.")
+	for _, unreachable := range strings.Split(os.Getenv("STUBVPN_UNREACHABLE"), ",") {
+		if unreachable != "" && unreachable == host {
+			fmt.Printf("  >> error: Connection attempt has failed due to server communication errors.\n")
+			fmt.Println("  >> state: Disconnected")
+			return
+		}
+	}
+	fmt.Println()
+	if os.Getenv("STUBVPN_HANG") != "" {
+		fmt.Println("  >> Please select a group.")
+		fmt.Print("Group: ")
+		time.Sleep(24 * time.Hour)
+	}
+
+	if os.Getenv("STUBVPN_UNTRUSTED") != "" {
+		fmt.Println()
+		fmt.Println("  >> Untrusted VPN Server Certificate!")
+		fmt.Println("  >> Certificate is from an untrusted source.")
+		fmt.Print("Connect Anyway? [y/n]: ")
+		if answer, _ := readLine(in); answer != "y" {
+			fmt.Println("  >> error: Connection attempt cancelled due to untrusted server certificate.")
+			fmt.Println("  >> state: Disconnected")
+			return
+		}
+		fmt.Print("Always trust this server and import the certificate? [y/n]: ")
+		readLine(in)
+	}
+
+	if os.Getenv("STUBVPN_SSO") != "" {
+		fmt.Println("  >> error: The SAML authentication method requires an external browser, which is not supported by the CLI.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	fmt.Println("  >> Please enter your username and password.")
+
+	fmt.Print("Username: ")
+	username, _ := readLine(in)
+	fmt.Print("Password: ")
+	password, _ := readLine(in)
+
+	if os.Getenv("STUBVPN_CRASH") != "" {
+		fmt.Fprintln(os.Stderr, "vpn: lost contact with the VPN agent process")
+		os.Exit(3)
+	}
+
+	if os.Getenv("STUBVPN_EXPIRED") != "" {
+		fmt.Println("  >> Your password has expired.")
+		fmt.Print("New Password: ")
+		readLine(in)
+		fmt.Print("Verify Password: ")
+		readLine(in)
+		fmt.Println("  >> Login failed.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	if second := os.Getenv("STUBVPN_SECOND_PASSWORD"); second != "" {
+		fmt.Print("Second Password: ")
+		if answer, _ := readLine(in); answer != second {
+			fmt.Println("  >> Login failed.")
+			fmt.Println("  >> state: Disconnected")
+			return
+		}
+	}
+
+	method := ""
+	if os.Getenv("STUBVPN_NO_MFA") == "" {
+		method = duoPrompt(in, username)
+		if !acceptedMethod(method) {
+			fmt.Println("  >> Login failed.")
+			fmt.Println("  >> state: Disconnected")
+			return
+		}
+	}
+
+	if password != envOr("STUBVPN_PASSWORD", "password") {
+		fmt.Println("  >> Login failed.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	if os.Getenv("STUBVPN_SESSION_LIMIT") != "" {
+		fmt.Println("  >> Login denied. The maximum number of simultaneous logins for this user has been reached.")
+		fmt.Println("  >> Login failed.")
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	fmt.Println("  >> state: Connecting")
+	fmt.Println("  >> notice: Establishing VPN session...")
+	fmt.Println()
+	fmt.Println(envOr("STUBVPN_BANNER", "Authorized use only."))
+	fmt.Println()
+	fmt.Print("accept? [y/n]: ")
+	answer, _ := readLine(in)
+	if answer != "y" {
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+
+	setConnected(host)
+	fmt.Println("  >> state: Connected")
+}
+
+// duoPrompt asks for the Duo passcode or option and returns the answer
+func duoPrompt(in *bufio.Scanner, username string) string {
+	fmt.Println("Duo two-factor login for " + username)
+	fmt.Println()
+	fmt.Println("Enter a passcode or select one of the following options:")
+	fmt.Println()
+	devices := envOr("STUBVPN_DEVICES", "Duo Push to XXX-XXX-1234,Phone call to XXX-XXX-1234,SMS passcodes to XXX-XXX-1234")
+	for i, device := range strings.Split(devices, ",") {
+		fmt.Printf(" %d. %s\n", i+1, device)
+	}
+	fmt.Println()
+	fmt.Print("Second Password: ")
+	method, _ := readLine(in)
+	if delay, err := time.ParseDuration(os.Getenv("STUBVPN_DUO_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+	return method
+}
+
+// acceptedMethod checks a Duo answer against STUBVPN_METHODS
+func acceptedMethod(method string) bool {
+	for _, m := range strings.Split(envOr("STUBVPN_METHODS", "push,sms,phone"), ",") {
+		if strings.TrimSpace(m) == method {
+			return true
+		}
+	}
+	return false
+}
+
+// readLine reads one line of scripted input
+func readLine(in *bufio.Scanner) (string, bool) {
+	if !in.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(in.Text()), true
+}
+
+// printState prints the current connection state like "vpn status"
+func printState() {
+	if host := connectedHost(); host != "" {
+		fmt.Println("  >> state: Connected")
+		fmt.Printf("  >> notice: Connected to %s.\n", host)
+		return
+	}
+	fmt.Println("  >> state: Disconnected")
+	fmt.Println("  >> notice: Ready to connect.")
+}
+
+// printStats prints a subset of "vpn stats"
+func printStats() {
+	host := connectedHost()
+	if host == "" {
+		fmt.Println("  >> state: Disconnected")
+		return
+	}
+	fmt.Println("[ Connection Information ]")
+	fmt.Println()
+	fmt.Println("    Tunnel State:              Connected")
+	fmt.Println("    Tunnel Mode (IPv4):        Split Include")
+	fmt.Println("    Duration:                  00:05:00")
+	fmt.Println()
+	fmt.Println("[ Address Information ]")
+	fmt.Println()
+	fmt.Println("    Client Address (IPv4):     10.0.0.2")
+	fmt.Printf("    Server Address:            %s\n", host)
+	fmt.Println()
+	fmt.Println("[ Bytes ]")
+	fmt.Println()
+	var sent, received int64 = 1024, 2048
+	if info, err := os.Stat(statePath()); err == nil && os.Getenv("STUBVPN_TRAFFIC") != "" {
+		up := time.Since(info.ModTime()).Seconds()
+		sent += int64(up * 1500)
+		received += int64(up * 12000)
+	}
+	fmt.Printf("    Bytes Sent:                %d\n", sent)
+	fmt.Printf("    Bytes Received:            %d\n", received)
+	fmt.Println()
+	fmt.Println("[ Transport Information ]")
+	fmt.Println()
+	fmt.Println("    Protocol:                  DTLSv1.2")
+	fmt.Println("    Protocol Cipher:           ECDHE_RSA_AES_256_GCM_SHA384")
+}
+
+// statePath returns the file holding the connected host
+func statePath() string {
+	return envOr("STUBVPN_STATE", filepath.Join(os.TempDir(), "stubvpn-state"))
+}
+
+// connectedHost returns the connected host, or "" when disconnected
+func connectedHost() string {
+	data, err := os.ReadFile(statePath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setConnected records the connected host, or clears it when host is ""
+func setConnected(host string) {
+	if host == "" {
+		os.Remove(statePath())
+		return
+	}
+	if err := os.WriteFile(statePath(), []byte(host+"\n"), 0600); err != nil {
+		fmt.Printf("  >> error: %v\n", err)
+	}
+}
+
+// envOr returns the environment variable name, or fallback if it is unset
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
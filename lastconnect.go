@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// lastConnectFile holds the parameters of the most recent successful connect
+const lastConnectFile = "last-connect.json"
+
+// lastConnect is what "connect --last", or a bare "connect", reuses. A
+// one-time passcode, or a method typed in with --interactive-prompts, is
+// never saved as the method.
+type lastConnect struct {
+	Profile  string    `json:"profile,omitempty"`
+	Host     string    `json:"host"`
+	Username string    `json:"username"`
+	Method   string    `json:"method,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// saveLastConnect records a successful connect. Failing to record it is only
+// logged, since the tunnel is up either way.
+func saveLastConnect(profileName string, result connectResult, username string) {
+	path, err := statePath(lastConnectFile)
+	if err != nil {
+		slog.Debug("failed to record last connect", "error", err)
+		return
+	}
+	last := lastConnect{Profile: profileName, Host: result.Host, Username: username, Time: time.Now()}
+	switch result.Method {
+	case "none", "passcode", "interactive":
+	default:
+		last.Method = result.Method
+	}
+	data, _ := json.Marshal(last)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		slog.Debug("failed to record last connect", "error", err)
+	}
+}
+
+// loadLastConnect returns the recorded connect, or nil if there is none
+func loadLastConnect() *lastConnect {
+	path, err := statePath(lastConnectFile)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var last lastConnect
+	if err := json.Unmarshal(data, &last); err != nil || last.Host == "" {
+		slog.Debug("ignoring unreadable last connect", "path", path, "error", err)
+		return nil
+	}
+	return &last
+}
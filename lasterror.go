@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// lastErrorFile holds the most recent connect failure
+const lastErrorFile = "last-error.json"
+
+// lastError is a connect failure persisted for later runs of status
+type lastError struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// saveLastError records a connect failure. Failing to record it is only
+// logged, since the original error matters more.
+func saveLastError(err error) {
+	path, pathErr := statePath(lastErrorFile)
+	if pathErr != nil {
+		slog.Debug("failed to record last error", "error", pathErr)
+		return
+	}
+	data, _ := json.Marshal(lastError{Message: err.Error(), Time: time.Now()})
+	if writeErr := os.WriteFile(path, data, 0600); writeErr != nil {
+		slog.Debug("failed to record last error", "error", writeErr)
+	}
+}
+
+// clearLastError forgets the recorded failure after a successful connect
+func clearLastError() {
+	path, err := statePath(lastErrorFile)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("failed to clear last error", "error", err)
+	}
+}
+
+// loadLastError returns the recorded failure, or nil if there is none
+func loadLastError() *lastError {
+	path, err := statePath(lastErrorFile)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var last lastError
+	if err := json.Unmarshal(data, &last); err != nil {
+		slog.Debug("ignoring unreadable last error", "path", path, "error", err)
+		return nil
+	}
+	return &last
+}
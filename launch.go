@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// launchError is a client that couldn't be started at all, as opposed to
+// one that ran and failed. Err is the error from starting it, usually an
+// *exec.Error or *fs.PathError.
+type launchError struct {
+	Path string
+	Err  error
+}
+
+// Error explains the likely cause; another attempt won't get further
+func (e *launchError) Error() string {
+	switch {
+	case isWrongArch(e.Err) && runtime.GOOS == "darwin" && runtime.GOARCH == "arm64":
+		return fmt.Sprintf("cannot run %s (%v): it is probably built for Intel Macs; install Rosetta with "+
+			"\"softwareupdate --install-rosetta\", or install the Apple Silicon build of Cisco Secure Client", e.Path, e.Err)
+	case isWrongArch(e.Err):
+		return fmt.Sprintf("cannot run %s (%v): it is probably built for a different architecture than this %s/%s "+
+			"machine; install the Cisco Secure Client build for it, or point --vpn-exec at the right binary",
+			e.Path, e.Err, runtime.GOOS, runtime.GOARCH)
+	case errors.Is(e.Err, exec.ErrNotFound):
+		return fmt.Sprintf("cannot run %s: it was not found; check --vpn-exec, or that Cisco Secure Client is installed", e.Path)
+	}
+	// A file that is there but "doesn't exist" is missing its loader
+	if _, err := os.Stat(e.Path); err == nil && errors.Is(e.Err, fs.ErrNotExist) {
+		return fmt.Sprintf("cannot run %s (%v): its loader is missing, which usually means it is built for a "+
+			"different architecture (e.g. 32-bit without the 32-bit libraries installed)", e.Path, e.Err)
+	}
+	return fmt.Sprintf("cannot run %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the error from starting the client
+func (e *launchError) Unwrap() error {
+	return e.Err
+}
+
+// isWrongArch checks for the errors an OS gives for a binary it can't load
+func isWrongArch(err error) bool {
+	for _, target := range archErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// explainLaunch turns a failure to start the client into a launchError.
+// Errors from a client that did start, like a nonzero exit, pass through.
+func explainLaunch(path string, err error) error {
+	var exitErr *exec.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	if errors.As(err, &execErr) || errors.As(err, &pathErr) {
+		return &launchError{Path: path, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,7 @@
+package main
+
+import "syscall"
+
+// archErrors are what starting a binary for another architecture fails
+// with; EBADARCH is "bad CPU type in executable", as when Rosetta is missing
+var archErrors = []error{syscall.ENOEXEC, syscall.EBADARCH}
@@ -0,0 +1,8 @@
+//go:build !darwin && !windows
+
+package main
+
+import "syscall"
+
+// archErrors are what starting a binary for another architecture fails with
+var archErrors = []error{syscall.ENOEXEC}
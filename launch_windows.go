@@ -0,0 +1,6 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+// archErrors are what starting a binary for another architecture fails with
+var archErrors = []error{windows.ERROR_BAD_EXE_FORMAT, windows.ERROR_EXE_MACHINE_TYPE_MISMATCH}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// leaseFile describes the session seccli connected, for other local tools
+const leaseFile = "lease.json"
+
+// leaseSchemaVersion is bumped whenever the lease fields change
+const leaseSchemaVersion = 2
+
+// connectionLease is the session seccli last connected. PID is the seccli
+// process that connected; the session outlives it, since the Cisco agent
+// keeps the tunnel up.
+type connectionLease struct {
+	SchemaVersion int       `json:"schema_version"`
+	Host          string    `json:"host"`
+	Server        string    `json:"server,omitempty"`
+	Interface     string    `json:"interface,omitempty"`
+	ClientAddress string    `json:"client_address,omitempty"`
+	Method        string    `json:"method"`
+	PID           int       `json:"pid"`
+	ConnectedAt   time.Time `json:"connected_at"`
+}
+
+// writeLease records a connected session, replacing the file atomically so
+// readers never see it half written. Failures are only logged, since the
+// tunnel is up either way.
+func writeLease(result connectResult) {
+	path, err := statePath(leaseFile)
+	if err != nil {
+		slog.Debug("failed to write lease", "error", err)
+		return
+	}
+	data, _ := json.MarshalIndent(connectionLease{
+		SchemaVersion: leaseSchemaVersion,
+		Host:          result.Host,
+		Server:        result.Server,
+		Interface:     result.Interface,
+		ClientAddress: result.ClientAddress,
+		Method:        result.Method,
+		PID:           os.Getpid(),
+		ConnectedAt:   time.Now().UTC(),
+	}, "", "  ")
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+leaseFile+".*")
+	if err != nil {
+		slog.Debug("failed to write lease", "error", err)
+		return
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// Other local tools are meant to read it
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		slog.Debug("failed to write lease", "error", err)
+	}
+}
+
+// readLease returns the recorded session, or nil if there is none
+func readLease() *connectionLease {
+	path, err := statePath(leaseFile)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lease connectionLease
+	if err := json.Unmarshal(data, &lease); err != nil {
+		slog.Debug("ignoring unreadable lease", "path", path, "error", err)
+		return nil
+	}
+	return &lease
+}
+
+// removeLease deletes the lease once the session is gone
+func removeLease() {
+	path, err := statePath(leaseFile)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("failed to remove lease", "error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Verbosity levels selected by repeating -v
+const (
+	verbosityMilestones  = 1 // -v: progress messages
+	verbosityChildOutput = 2 // -vv: also the VPN tool's own output
+	verbosityDebug       = 3 // -vvv: also debug details
+)
+
+// verbosity counts the -v flags given
+var verbosity int
+
+// Log formats selected by --log-format
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// secretLogKeys are attribute keys whose values never reach the log, as a
+// safety net behind the redaction at the call sites
+var secretLogKeys = map[string]bool{"password": true, "passcode": true, "proxy_auth": true}
+
+// validateLogFormat checks the --log-format value
+func validateLogFormat(format string) error {
+	switch format {
+	case logFormatText, logFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid log format %q (expected %s or %s)", format, logFormatText, logFormatJSON)
+}
+
+// setupLogging installs the default slog logger for the chosen verbosity
+// and format
+func setupLogging(format string) {
+	level := slog.LevelWarn
+	switch {
+	case verbosity >= verbosityDebug:
+		level = slog.LevelDebug
+	case verbosity >= verbosityMilestones:
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if format == logFormatJSON {
+		// Aggregators key on "event" for what happened
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.MessageKey {
+				a.Key = "event"
+			}
+			return redactAttr(a)
+		}
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			return redactAttr(a)
+		}
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// redactAttr masks the value of attributes named like secrets
+func redactAttr(a slog.Attr) slog.Attr {
+	if secretLogKeys[a.Key] {
+		return slog.String(a.Key, "********")
+	}
+	return a
+}
@@ -1,60 +1,105 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/term"
 )
 
+// Cisco VPN client products that --client can select
+const (
+	clientAnyConnect    = "anyconnect"
+	clientSecureClient  = "secure-client"
+	clientUnknownVendor = ""
+)
+
+// vpnCandidate is a well-known install location of a VPN client product
+type vpnCandidate struct {
+	path   string
+	client string
+}
+
 // findVPNExec attempts to locate the Cisco Secure Client VPN executable
-// depending on the OS. Falls back to PATH lookup if unknown.
-func findVPNExec() (string, error) {
+// depending on the OS. Falls back to PATH lookup if unknown. When client
+// is non-empty, only that product's install locations are considered.
+// The extra paths are tried first, whatever the client.
+func findVPNExec(client string, extra []string) (string, error) {
+	for _, path := range extra {
+		found := fileExists(path) && isExecutable(path)
+		slog.Debug("checked custom VPN executable candidate", "path", path, "found", found)
+		if found {
+			return path, nil
+		}
+	}
+
 	osType := runtime.GOOS
-	var candidates []string
+	var candidates []vpnCandidate
 
 	switch osType {
 	case "darwin": // macOS
-		candidates = []string{
-			"/opt/cisco/secureclient/bin/vpn",
-			"/Applications/Cisco/Cisco Secure Client.app/Contents/MacOS/vpn",
-			"/Applications/Cisco AnyConnect Secure Mobility Client.app/Contents/MacOS/vpn",
+		candidates = []vpnCandidate{
+			{"/opt/cisco/secureclient/bin/vpn", clientSecureClient},
+			{"/Applications/Cisco/Cisco Secure Client.app/Contents/MacOS/vpn", clientSecureClient},
+			{"/Applications/Cisco AnyConnect Secure Mobility Client.app/Contents/MacOS/vpn", clientAnyConnect},
 		}
 	case "linux":
-		candidates = []string{
-			"/opt/cisco/secureclient/bin/vpn",
-			"/opt/cisco/anyconnect/bin/vpn",
-			"/usr/local/bin/vpn",
-			"/usr/bin/vpn",
+		candidates = []vpnCandidate{
+			{"/opt/cisco/secureclient/bin/vpn", clientSecureClient},
+			{"/opt/cisco/anyconnect/bin/vpn", clientAnyConnect},
+			{"/usr/local/bin/vpn", clientUnknownVendor},
+			{"/usr/bin/vpn", clientUnknownVendor},
 		}
 	case "windows":
-		candidates = []string{
-			`C:\Program Files (x86)\Cisco\Cisco Secure Client\vpncli.exe`,
-			`C:\Program Files (x86)\Cisco\Cisco AnyConnect Secure Mobility Client\vpncli.exe`,
-			`C:\Program Files\Cisco\Cisco Secure Client\vpncli.exe`,
-			`C:\Program Files\Cisco\Cisco AnyConnect Secure Mobility Client\vpncli.exe`,
+		candidates = []vpnCandidate{
+			{`C:\Program Files (x86)\Cisco\Cisco Secure Client\vpncli.exe`, clientSecureClient},
+			{`C:\Program Files (x86)\Cisco\Cisco AnyConnect Secure Mobility Client\vpncli.exe`, clientAnyConnect},
+			{`C:\Program Files\Cisco\Cisco Secure Client\vpncli.exe`, clientSecureClient},
+			{`C:\Program Files\Cisco\Cisco AnyConnect Secure Mobility Client\vpncli.exe`, clientAnyConnect},
 		}
 	}
 
 	// Check each candidate
-	for _, path := range candidates {
-		if fileExists(path) && isExecutable(path) {
-			return path, nil
+	for _, candidate := range candidates {
+		if client != "" && candidate.client != client {
+			continue
+		}
+		found := fileExists(candidate.path) && isExecutable(candidate.path)
+		slog.Debug("checked VPN executable candidate", "path", candidate.path, "found", found)
+		if found {
+			return candidate.path, nil
 		}
 	}
 
+	// The PATH fallback can't tell the products apart
+	if client != "" {
+		return "", fmt.Errorf("could not locate %s executable; pass --vpn-exec or omit --client", clientName(client))
+	}
+
 	// Fallback: try PATH lookup
 	vpnExecs := []string{"vpn", "vpncli"}
 	for _, execName := range vpnExecs {
 		if path, err := exec.LookPath(execName); err == nil {
+			slog.Debug("found VPN executable in PATH", "path", path)
 			return path, nil
 		}
 	}
@@ -62,6 +107,29 @@ func findVPNExec() (string, error) {
 	return "", fmt.Errorf("could not locate Cisco Secure Client/AnyConnect executable")
 }
 
+// extraExecPaths returns the custom candidate paths from VPN_EXEC_PATHS
+// (separated like PATH) and the config's vpn_exec_paths
+func extraExecPaths(cfg *config) []string {
+	var paths []string
+	for _, path := range filepath.SplitList(os.Getenv("VPN_EXEC_PATHS")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return append(paths, cfg.VPNExecPaths...)
+}
+
+// clientName returns the product name for a --client value
+func clientName(client string) string {
+	switch client {
+	case clientAnyConnect:
+		return "Cisco AnyConnect"
+	case clientSecureClient:
+		return "Cisco Secure Client"
+	}
+	return client
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -77,93 +145,655 @@ func isExecutable(path string) bool {
 	return info.Mode()&0111 != 0
 }
 
-// runCommand executes a command and returns its output
+// runCommand executes a command and returns its output. Stderr is included,
+// as some VPN client versions print their status there.
 func runCommand(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	output, err := cmd.Output()
+	return runCommandCtx(commandCtx, name, args...)
+}
+
+// runCommandCtx is runCommand bounded by ctx, which must derive from
+// commandCtx so --timeout-all still applies
+func runCommandCtx(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Don't wait on output pipes held open by a killed client's children
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+// connState is what "vpn status" says about the tunnel
+type connState int
+
+const (
+	stateUnknown connState = iota // status failed or was unrecognizable
+	stateDisconnected
+	stateConnected
+)
+
+// vpnState asks the client for the state of the tunnel
+func vpnState(vpnExec string) connState {
+	state, _ := vpnStateOutput(vpnExec)
+	return state
+}
+
+// vpnStateCtx is vpnState with "vpn status" bounded by ctx
+func vpnStateCtx(ctx context.Context, vpnExec string) connState {
+	state, _ := vpnStateOutputCtx(ctx, vpnExec)
+	return state
+}
+
+// vpnStateOutput is vpnState along with the "vpn status" output it read,
+// which is empty if the state was assumed or the command failed
+func vpnStateOutput(vpnExec string) (connState, string) {
+	return vpnStateOutputCtx(commandCtx, vpnExec)
+}
+
+// vpnStateOutputCtx is vpnStateOutput with "vpn status" bounded by ctx
+func vpnStateOutputCtx(ctx context.Context, vpnExec string) (connState, string) {
+	if assumedState != nil {
+		return *assumedState, ""
+	}
+	output, err := runCommandCtx(ctx, vpnExec, "status")
+	if err != nil {
+		slog.Debug("VPN status command failed", "error", err)
+		return stateUnknown, ""
+	}
+	return connStateOf(output), output
+}
+
 // vpnConnected checks if VPN is currently connected
 func vpnConnected(vpnExec string) bool {
-	output, err := runCommand(vpnExec, "status")
+	return vpnState(vpnExec) == stateConnected
+}
+
+// statusMarkers are the words in "vpn status" output that tell the state;
+// the config's state_markers replace them
+var statusMarkers = stateMarkers{Connected: []string{"Connected"}, Disconnected: []string{"Disconnected"}}
+
+// setStateMarkers applies configured state markers, keeping the defaults
+// for a state with none. A connect also ends once the client reports a
+// custom connected state.
+func setStateMarkers(m *stateMarkers) {
+	if m == nil {
+		return
+	}
+	if connected := nonEmpty(m.Connected); len(connected) > 0 {
+		statusMarkers.Connected = connected
+		for _, marker := range connected {
+			connectEndMarkers = append(connectEndMarkers, endMarker{text: "state: " + strings.ToLower(marker)})
+		}
+	}
+	if disconnected := nonEmpty(m.Disconnected); len(disconnected) > 0 {
+		statusMarkers.Disconnected = disconnected
+	}
+}
+
+// nonEmpty drops empty strings, which would match any output
+func nonEmpty(values []string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConnectedOutput checks "vpn status" output for a connected state
+func isConnectedOutput(output string) bool {
+	return containsAny(output, statusMarkers.Connected)
+}
+
+// connStateOf classifies "vpn status" output
+func connStateOf(output string) connState {
+	switch {
+	case isConnectedOutput(output):
+		return stateConnected
+	case containsAny(output, statusMarkers.Disconnected):
+		return stateDisconnected
+	}
+	return stateUnknown
+}
+
+// statusSchemaVersion is bumped whenever the structured status fields change
+const statusSchemaVersion = 10
+
+// vpnStatus is the connection state reported by the status command
+type vpnStatus struct {
+	SchemaVersion int               `json:"schema_version"`
+	Connected     bool              `json:"connected"`
+	Fields        map[string]string `json:"fields,omitempty"`
+	// Transport is "dtls" or "tls" while connected, if the client says
+	Transport string `json:"transport,omitempty"`
+	// Lease is the session seccli connected, while it is still up
+	Lease     *connectionLease `json:"lease,omitempty"`
+	LastError *lastError       `json:"last_error,omitempty"`
+}
+
+// newVPNStatus builds a status tagged with the current schema version
+func newVPNStatus(connected bool) vpnStatus {
+	return vpnStatus{SchemaVersion: statusSchemaVersion, Connected: connected}
+}
+
+// String formats the status for text output
+func (s vpnStatus) String() string {
+	text := "VPN Connected: No"
+	if s.Connected {
+		text = "VPN Connected: Yes"
+	}
+	if s.Transport != "" {
+		text += "\nTransport: " + strings.ToUpper(s.Transport)
+	}
+	if s.Lease != nil {
+		text += fmt.Sprintf("\nConnected to %s since %s", s.Lease.Host, s.Lease.ConnectedAt.Local().Format(time.DateTime))
+	}
+	if s.LastError != nil {
+		text += fmt.Sprintf("\nLast error: %s at %s", s.LastError.Message, s.LastError.Time.Local().Format(time.DateTime))
+	}
+	return text
+}
+
+// errNoTerminal is returned by getPassword when there is no terminal to
+// prompt on, e.g. under cron, systemd or a pipe
+var errNoTerminal = errors.New("no terminal to prompt for the password on; seccli never caches passwords, so run it from an interactive terminal (or pipe the password to connect --stdin-password)")
+
+// readStdinPassword reads the password from the first line of a piped
+// stdin. It reads byte by byte so nothing past that line is consumed.
+func readStdinPassword() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("--stdin-password expects the password piped to stdin; leave it out to be prompted")
+	}
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read password from stdin: %v", err)
+		}
+	}
+	password := strings.TrimSuffix(string(line), "\r")
+	if password == "" {
+		return "", fmt.Errorf("no password on stdin")
+	}
+	return password, nil
+}
+
+// readSecondPasswordFile reads the second password from the first line of
+// a file
+func readSecondPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return false
+		return "", fmt.Errorf("failed to read second password: %v", err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	password := strings.TrimSuffix(line, "\r")
+	if password == "" {
+		return "", fmt.Errorf("no second password in %s", path)
 	}
-	return strings.Contains(output, "Connected")
+	return password, nil
 }
 
 // getPassword prompts for password input without echoing
 func getPassword(prompt string) (string, error) {
+	// Fail before printing a prompt nobody can answer
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", errNoTerminal
+	}
 	fmt.Print(prompt)
-	password, err := term.ReadPassword(int(syscall.Stdin))
+	password, err := readPassword()
 	fmt.Println() // Add newline after password input
 	if err != nil {
 		return "", err
 	}
-	return string(password), nil
+	return password, nil
+}
+
+// isPasscode checks if a method is a one-time Duo passcode rather than a
+// named method like push
+func isPasscode(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, r := range method {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// inlineMethodPattern matches what may follow a comma at the end of the
+// password with --inline-method, lowercased: a Duo method name or synonym,
+// optionally with a device number, or a passcode as passcodePattern allows
+var inlineMethodPattern = inlineMethodRegexp()
+
+func inlineMethodRegexp() *regexp.Regexp {
+	names := append(slices.Clone(duoMethods), slices.Sorted(maps.Keys(methodSynonyms))...)
+	passcode := strings.TrimSuffix(strings.TrimPrefix(passcodePattern.String(), "^"), "$")
+	return regexp.MustCompile(`^((` + strings.Join(names, "|") + `)\d?|` + passcode + `)$`)
+}
+
+// splitInlineMethod splits "password,method" into its parts. Anything after
+// the last comma that doesn't look like a method stays in the password, so
+// passwords with commas still work.
+func splitInlineMethod(input string) (string, string) {
+	i := strings.LastIndex(input, ",")
+	if i < 0 {
+		return input, ""
+	}
+	method := strings.ToLower(strings.TrimSpace(input[i+1:]))
+	if !inlineMethodPattern.MatchString(method) {
+		return input, ""
+	}
+	return input[:i], normalizeMethod(method)
+}
+
+// errAlreadyConnected is returned by connectVPN when there is nothing to do
+var errAlreadyConnected = errors.New("VPN is already connected")
+
+// connectOptions are the parameters of a connect attempt
+type connectOptions struct {
+	Host     string
+	Username string
+	Method   string // empty skips MFA, unless PasscodeCommand is set
+	Password string // prompted for if empty
+	Verbose  bool   // show the VPN tool's output
+	Strict   bool   // refuse to connect alongside another VPN
+
+	// MFARateLimit caps MFA attempts per hour; 0 disables the cap
+	MFARateLimit int
+
+	// Timeout bounds the scripted session; 0 means no limit. Without
+	// TimeoutSet, the method's default (or MethodTimeouts entry) applies.
+	Timeout        time.Duration
+	TimeoutSet     bool
+	MethodTimeouts map[string]time.Duration
+	// ConnectTimeout bounds the handshake, until the server asks for
+	// credentials; 0 means no limit
+	ConnectTimeout time.Duration
+
+	// Proxy is passed to the client through the proxy environment variables
+	Proxy *url.URL
+
+	// TrustedFingerprint is the SHA-256 fingerprint an untrusted server
+	// certificate must have to be accepted without asking
+	TrustedFingerprint string
+	// NoPrompt refuses to ask on the terminal mid-connect
+	NoPrompt bool
+	// InteractivePrompts leaves every prompt after the connect command to
+	// the user, showing the client's output, instead of answering them
+	InteractivePrompts bool
+
+	// ShowBanner prints the server's banner before accepting it;
+	// RequireBannerAck also asks on the terminal first
+	ShowBanner       bool
+	RequireBannerAck bool
+
+	// ExtraArgs are appended to the "vpn -s" command line as given
+	ExtraArgs []string
+
+	// CheckClock warns before connecting if the clock is off by more than
+	// MaxClockSkew compared to ClockReference (default: the gateway)
+	CheckClock     bool
+	ClockReference string
+	MaxClockSkew   time.Duration
+
+	// OnsiteProbe, if set, skips connecting when it is already reachable
+	OnsiteProbe string
+	// PortalProbe, if set, is fetched to catch a captive portal before
+	// connecting
+	PortalProbe string
+
+	// StatusRules extract the status fields cached once the connect is
+	// verified, as the status command would
+	StatusRules map[string]*regexp.Regexp
+
+	// RestartAgent restarts an unresponsive Cisco agent and retries once
+	RestartAgent bool
+	// Takeover disconnects a session already up, whoever started it, and
+	// connects in its place
+	Takeover bool
+
+	// Retry limits the attempts connectWithStrategy makes
+	Retry retryPolicy
+
+	// SecondPassword answers the first "Second Password:" prompt, for
+	// servers that ask for two passwords before Duo. AskSecondPassword
+	// reads it from the terminal if it is empty.
+	SecondPassword    string
+	AskSecondPassword bool
+
+	// PasscodeCommand prints a passcode to use when Method is empty. It is
+	// run anew for each attempt, since passcodes only work once.
+	PasscodeCommand string
+
+	// Attempt of Attempts is shown in the spinner when retrying
+	Attempt  int
+	Attempts int
+}
+
+// spinnerSuffix labels a spinner with the attempt number when retrying
+func (o connectOptions) spinnerSuffix(text string) string {
+	if o.Attempts > 1 {
+		return fmt.Sprintf(" %s (attempt %d/%d)...", text, o.Attempt, o.Attempts)
+	}
+	return " " + text + "..."
 }
 
 // connectVPN connects to the VPN
-func connectVPN(vpnExec, host, username, method string, verbose bool) error {
+func connectVPN(ctx context.Context, vpnExec string, opts connectOptions) (connectResult, error) {
 	// Start spinner for connection process
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	s.Suffix = " Checking VPN Status..."
+	s := newSpinner(opts.spinnerSuffix("Checking VPN Status"))
 	s.Start()
 	defer s.Stop()
 
 	if vpnConnected(vpnExec) {
-		return fmt.Errorf("VPN is already connected")
+		return connectResult{}, errAlreadyConnected
 	}
 
 	s.Stop()
 
-	password, err := getPassword("Enter VPN password: ")
-	if err != nil {
-		return fmt.Errorf("failed to read password: %v", err)
+	if opts.OnsiteProbe != "" && onsiteReachable(ctx, opts.OnsiteProbe) {
+		fmt.Fprintf(os.Stderr, "%s is reachable, so this machine is already on the internal network; skipping VPN connect\n", opts.OnsiteProbe)
+		return connectResult{}, errOnsite
 	}
 
-	// FIXME: this text is interrupted by the Duo (push/sms/phone): thing
-	// s = spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	// s.Suffix = " Connecting to VPN..."
-	// s.Start()
-	// defer s.Stop()
+	// A portal stops the retries, so checking once is enough
+	if opts.PortalProbe != "" && opts.Attempt <= 1 {
+		if err := checkCaptivePortal(ctx, opts.PortalProbe); err != nil {
+			return connectResult{}, err
+		}
+	}
+	if err := checkConflictingTunnels(opts.Strict); err != nil {
+		return connectResult{}, err
+	}
+	if err := checkSystemExtension(); err != nil {
+		return connectResult{}, err
+	}
+	if opts.CheckClock {
+		checkClockSkew(ctx, clockReferenceURL(opts.Host, opts.ClockReference), opts.MaxClockSkew)
+	}
 
-	// Create the script for VPN connection like Python version
-	script := fmt.Sprintf("connect %s\n%s\n%s\n%s\ny\nexit\n", host, username, password, method)
+	// The user answers everything, Duo included, as the client asks
+	if opts.InteractivePrompts {
+		opts.Method = ""
+	}
 
-	cmd := exec.Command(vpnExec, "-s")
-	cmd.Stdin = strings.NewReader(script)
+	// Throttle before prompting, so a refused attempt costs no typing
+	if opts.Method != "" || opts.PasscodeCommand != "" {
+		if err := reserveMFAAttempt(opts.MFARateLimit, time.Now()); err != nil {
+			return connectResult{}, err
+		}
+	}
 
-	if verbose {
-		// s.Stop() // Stop spinner if verbose mode to show VPN output
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	password := opts.Password
+	var err error
+	if password == "" && !opts.InteractivePrompts {
+		password, err = getPassword("Enter VPN password: ")
+		if err != nil {
+			return connectResult{}, fmt.Errorf("failed to read password: %v", err)
+		}
+	}
+	if opts.AskSecondPassword && opts.SecondPassword == "" && !opts.InteractivePrompts {
+		opts.SecondPassword, err = getPassword("Enter second password: ")
+		if err != nil {
+			return connectResult{}, fmt.Errorf("failed to read second password: %v", err)
+		}
 	}
 
-	err = cmd.Run()
+	// Fetch the passcode as late as possible, as codes expire quickly
+	if opts.Method == "" && opts.PasscodeCommand != "" {
+		opts.Method, err = runPasscodeCommand(ctx, opts.PasscodeCommand)
+		if err != nil {
+			return connectResult{}, err
+		}
+	}
+
+	slog.Info("connecting to VPN", "host", opts.Host, "username", opts.Username, "method", reportedMethod(opts.Method))
+
+	// The user may take their time answering, so only --timeout applies
+	wait := duoWaitFor(opts.Method, opts.MethodTimeouts)
+	switch {
+	case opts.TimeoutSet:
+	case opts.InteractivePrompts:
+		opts.Timeout = 0
+	default:
+		opts.Timeout = wait.timeout
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	args := append([]string{"-s"}, opts.ExtraArgs...)
+	if len(opts.ExtraArgs) > 0 {
+		slog.Info("passing extra arguments to VPN executable", "args", opts.ExtraArgs)
+	}
+	cmd := exec.CommandContext(ctx, vpnExec, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return connectResult{}, fmt.Errorf("failed to set up VPN command: %v", err)
+	}
+	// Don't wait forever on output pipes held open by a killed client
+	cmd.WaitDelay = time.Second
+	if opts.Proxy != nil {
+		slog.Info("connecting through proxy", "proxy", opts.Proxy.Redacted())
+		cmd.Env = append(os.Environ(), proxyEnv(opts.Proxy)...)
+	}
+
+	// Capture the output so known failures can be explained, and watch it
+	// for the end of the handshake. Depending on the version, the client
+	// prompts on stdout or stderr, so both share one writer: exec then
+	// feeds them through one pipe, keeping their text in order.
+	var abortErr error
+	var output bytes.Buffer
+	s = newSpinner(opts.spinnerSuffix("Connecting to VPN"))
+	var driver interface {
+		io.Writer
+		Close()
+	}
+	if opts.InteractivePrompts {
+		driver = newInteractiveDriver(stdin, "connect "+opts.Host)
+	} else {
+		driver = newPromptDriver(stdin, "connect "+opts.Host, connectSteps(opts, password, &abortErr, &output, s))
+	}
+	handshake := newPhaseWatcher(handshakeMarkers)
+	duoPrompt := newPhaseWatcher(duoPromptMarkers)
+	writer := io.MultiWriter(&output, handshake, duoPrompt, driver)
+	if opts.Verbose || opts.InteractivePrompts {
+		writer = io.MultiWriter(os.Stdout, &output, handshake, duoPrompt, driver)
+	} else {
+		// The spinner would garble the client's output, so it only runs
+		// when that is hidden
+		s.Start()
+	}
+	secrets := []string{password, opts.SecondPassword}
+	if isPasscode(opts.Method) {
+		secrets = append(secrets, opts.Method)
+	}
+	writer = withChildLog(writer, "connect "+opts.Host, secrets...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	// Enforce --connect-timeout until the server asks for credentials,
+	// unless the user may be answering a prompt before that
+	var handshakeTimedOut atomic.Bool
+	if opts.ConnectTimeout > 0 && !opts.InteractivePrompts {
+		go func() {
+			timer := time.NewTimer(opts.ConnectTimeout)
+			defer timer.Stop()
+			select {
+			case <-handshake.done:
+			case <-ctx.Done():
+			case <-timer.C:
+				handshakeTimedOut.Store(true)
+				cancel()
+			}
+		}()
+	}
+
+	// Count down the time left to answer a push or call
+	if deadline, ok := ctx.Deadline(); ok && countsDown(opts.Method) {
+		go func() {
+			select {
+			case <-duoPrompt.done:
+				showCountdown(ctx, s, opts.spinnerSuffix, wait.message, deadline)
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	// The cache is replaced with the verified state on success, and
+	// dropped otherwise
+	started := time.Now()
+	verified := false
+	defer func() {
+		if !verified {
+			clearStatusCache()
+		}
+	}()
+	err = runTracked(cmd)
+	driver.Close()
+	s.Stop()
+	var launch *launchError
+	if errors.As(err, &launch) {
+		return connectResult{}, err
+	}
+	if abortErr != nil {
+		return connectResult{}, abortErr
+	}
+	// A tunnel that came up anyway means the match was something else
+	if failure := detectConnectFailure(output.String()); failure != nil {
+		if state, _ := vpnStateOutput(vpnExec); state != stateConnected {
+			return connectResult{}, failure
+		}
+		slog.Debug("connected despite a known failure in the output", "failure", failure)
+	}
+	if handshakeTimedOut.Load() {
+		return connectResult{}, fmt.Errorf("VPN connect timed out after %s in the handshake phase (waiting for the server to ask for credentials); the client's last output was:\n%s",
+			opts.ConnectTimeout, indent(lastLines(output.String(), 5), "  "))
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if countsDown(opts.Method) && duoPrompt.reached() {
+			return connectResult{}, fmt.Errorf("%w after %s: %s; connect again, or allow longer with --timeout",
+				errDuoTimeout, opts.Timeout, wait.expired)
+		}
+		phase := "handshake phase"
+		if handshake.reached() {
+			phase = "authentication phase: " + wait.expired
+		}
+		return connectResult{}, fmt.Errorf("VPN connect timed out after %s in the %s; the client's last output was:\n%s",
+			opts.Timeout, phase, indent(lastLines(output.String(), 5), "  "))
+	}
+	if opts.Method == "" && !opts.InteractivePrompts && promptedForMFA(output.String()) {
+		return connectResult{}, fmt.Errorf("the server asked for a second factor despite --no-mfa; retry without --no-mfa")
+	}
 	if err != nil {
-		return fmt.Errorf("VPN command failed: %v", err)
+		return connectResult{}, fmt.Errorf("VPN command failed: %v; the client's last output was:\n%s",
+			err, indent(lastLines(output.String(), 5), "  "))
 	}
 
 	// Check if connection was successful
-	if !vpnConnected(vpnExec) {
-		return fmt.Errorf("VPN connection failed")
+	slog.Info("verifying VPN connection")
+	state, statusOutput := vpnStateOutput(vpnExec)
+	if state != stateConnected {
+		return connectResult{}, fmt.Errorf("VPN connection failed")
+	}
+
+	// Report the method Duo was answered with; a server that never asked
+	// used none, whatever was requested
+	used := reportedMethod(opts.Method)
+	switch {
+	case opts.InteractivePrompts:
+		used = "interactive"
+	case !duoPrompt.reached():
+		used = reportedMethod("")
+	}
+	elapsed := time.Since(started)
+	slog.Info("VPN connected", "host", opts.Host, "method", used, "duration", elapsed.String())
+	recordConnect(opts.Host, used, elapsed)
+	result := newConnectResult(vpnExec, used, elapsed)
+
+	// A status right after the connect can reuse the check just made
+	status := result.vpnStatus
+	status.Fields = parseStatus(statusOutput, opts.StatusRules)
+	saveStatusCache(vpnExec, status, false)
+	verified = true
+	return result, nil
+}
+
+// confirmDisconnect shows the current connection and asks the user to
+// confirm tearing it down
+func confirmDisconnect(vpnExec string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("disconnect requires confirmation; pass --yes to skip it")
+	}
+
+	if stats, err := getStats(vpnExec); err == nil && stats.Server != "" {
+		fmt.Printf("Connected to %s", stats.Server)
+		if stats.Duration != "" {
+			fmt.Printf(" for %s", stats.Duration)
+		}
+		if stats.ClientAddress != "" {
+			fmt.Printf(" (address %s)", stats.ClientAddress)
+		}
+		fmt.Println()
 	}
+	fmt.Println("Other users of this machine will lose the VPN connection too.")
 
+	ok, err := askYesNo("Disconnect? [y/N]: ")
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("disconnect cancelled")
+	}
 	return nil
 }
 
-// disconnectVPN disconnects from the VPN
-func disconnectVPN(vpnExec string, verbose bool) error {
+// askYesNo prompts for a yes/no answer, defaulting to no
+func askYesNo(prompt string) (bool, error) {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
 
-	// FIXME: this code is duplicated
-	// Start spinner for connection process
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	s.Suffix = " Checking VPN Status..."
+// disconnectVPN disconnects from the VPN. before, if set, runs once the
+// disconnect is confirmed and the session is still up.
+func disconnectVPN(vpnExec string, verbose, confirm bool, before func()) error {
+
+	s := newSpinner(" Checking VPN Status...")
 	s.Start()
 	defer s.Stop()
 
@@ -173,31 +803,54 @@ func disconnectVPN(vpnExec string, verbose bool) error {
 
 	s.Stop()
 
+	if confirm {
+		if err := confirmDisconnect(vpnExec); err != nil {
+			return err
+		}
+	}
+	if before != nil {
+		before()
+	}
+
 	// Start spinner for connection process
-	s = spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	s.Suffix = " Disconnecting from VPN..."
+	s = newSpinner(" Disconnecting from VPN...")
 	s.Start()
 	defer s.Stop()
 
+	slog.Info("disconnecting from VPN")
 	script := "disconnect\nexit\n"
-	cmd := exec.Command(vpnExec, "-s")
+	cmd := exec.CommandContext(commandCtx, vpnExec, "-s")
 	cmd.Stdin = strings.NewReader(script)
 
+	// Capture the output to explain a failure. As for connect, both
+	// streams share one writer, since the client may report on either.
+	var output bytes.Buffer
+	var out io.Writer = &output
 	if verbose {
 		s.Stop() // Stop spinner if verbose mode to show VPN output
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		out = io.MultiWriter(os.Stdout, &output)
 	}
+	out = withChildLog(out, "disconnect")
+	cmd.Stdout, cmd.Stderr = out, out
 
-	err := cmd.Run()
+	defer clearStatusCache()
+	err := runTracked(cmd)
+	var launch *launchError
+	if errors.As(err, &launch) {
+		return err
+	}
 	if err != nil {
-		return fmt.Errorf("VPN disconnect command failed: %v", err)
+		return fmt.Errorf("VPN disconnect command failed: %v; the client's last output was:\n%s",
+			err, indent(lastLines(output.String(), 5), "  "))
 	}
 
 	// Check if disconnection was successful
+	slog.Info("verifying VPN disconnection")
 	if vpnConnected(vpnExec) {
 		return fmt.Errorf("VPN disconnection failed")
 	}
+	recordDisconnect()
+	removeLease()
 
 	return nil
 }
@@ -205,160 +858,1465 @@ func disconnectVPN(vpnExec string, verbose bool) error {
 // getVPNExec gets the VPN executable path from context or auto-detects it
 func getVPNExec(cmd *cli.Command) (string, error) {
 	vpnExec := cmd.String("vpn-exec")
+	var extra []string
 	if vpnExec == "" {
-		return findVPNExec()
+		cfg, err := loadConfig(cmd.String("config"))
+		if err != nil {
+			return "", err
+		}
+		vpnExec = cfg.VPNExec
+		extra = extraExecPaths(cfg)
+	}
+	if vpnExec == "" {
+		client := cmd.String("client")
+		switch client {
+		case "", clientAnyConnect, clientSecureClient:
+		default:
+			return "", fmt.Errorf("invalid --client %q (expected %s or %s)", client, clientAnyConnect, clientSecureClient)
+		}
+		if !cmd.Bool("force-exec-recheck") {
+			if cached := cachedVPNExec(client); cached != "" {
+				return cached, nil
+			}
+		}
+		found, err := findVPNExec(client, extra)
+		if err != nil && assumedState != nil {
+			// The state is assumed in tests, which may have no client
+			slog.Debug("no VPN client; relying on the assumed state", "error", err)
+			return "vpn", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		saveVPNExec(client, found)
+		return found, nil
 	}
 	return vpnExec, nil
 }
 
-// connectAction handles the connect command
-func connectAction(ctx context.Context, cmd *cli.Command) error {
+// resolveTarget works out the host, username and method from the flags,
+// a --uri, a --profile and a --profile-file (or an installed one), in
+// that order of precedence.
+// A method not given by the flags, URI, profile or last connect comes from
+// VPN_METHOD, then the config's os_methods, then defaults to push.
+func resolveTarget(cmd *cli.Command) (string, string, string, error) {
 	username := cmd.String("username")
 	vpnHost := cmd.String("vpn-host")
 	method := cmd.String("method")
-	verbose := cmd.Bool("verbose")
 
-	if username == "" {
-		return fmt.Errorf("--username is required for connect command")
+	methodSet := cmd.IsSet("method")
+
+	// Flags given explicitly take precedence over the URI
+	if raw := cmd.String("uri"); raw != "" {
+		uri, err := parseConnectURI(raw)
+		if err != nil {
+			return "", "", "", err
+		}
+		if !cmd.IsSet("vpn-host") {
+			vpnHost = uri.Host
+		}
+		if !cmd.IsSet("username") && uri.Username != "" {
+			username = uri.Username
+		}
+		if !methodSet && uri.Method != "" {
+			method = uri.Method
+			methodSet = true
+		}
 	}
-	if vpnHost == "" {
-		return fmt.Errorf("--vpn-host is required for connect command")
+
+	// A saved profile fills in whatever is still missing
+	if name := cmd.String("profile"); name != "" {
+		p, err := lookupProfile(cmd.String("config"), name)
+		if err != nil {
+			return "", "", "", err
+		}
+		if vpnHost == "" {
+			vpnHost = p.Host
+		}
+		if username == "" {
+			username = p.Username
+		}
+		if !methodSet && p.Method != "" {
+			method = p.Method
+			methodSet = true
+		}
 	}
 
-	vpnExec, err := getVPNExec(cmd)
-	if err != nil {
-		return err
+	// The last successful connect fills in the rest with --last, and is the
+	// target of a bare "connect"
+	if cmd.Bool("last") || (cmd.Name == "connect" && !targetGiven(cmd)) {
+		last := loadLastConnect()
+		switch {
+		case last != nil:
+			slog.Info("using the last connection", "host", last.Host, "username", last.Username, "profile", last.Profile)
+			if vpnHost == "" {
+				vpnHost = last.Host
+			}
+			if username == "" {
+				username = last.Username
+			}
+			if !methodSet && last.Method != "" {
+				method = last.Method
+				methodSet = true
+			}
+		case cmd.Bool("last"):
+			return "", "", "", fmt.Errorf("--last found no successful connect to reuse")
+		}
 	}
 
-	err = connectVPN(vpnExec, vpnHost, username, method, verbose)
-	if err != nil {
-		return err
+	// Only then the environment, which beats the config
+	if env := os.Getenv("VPN_METHOD"); !methodSet && env != "" {
+		method = env
+		methodSet = true
+	}
+	if !methodSet {
+		cfg, err := loadConfig(cmd.String("config"))
+		if err != nil {
+			return "", "", "", err
+		}
+		if m := cfg.OSMethods[runtime.GOOS]; m != "" {
+			slog.Debug("using the config's default method for this OS", "os", runtime.GOOS, "method", reportedMethod(m))
+			method = m
+		}
 	}
 
-	fmt.Println("VPN connection successful")
-	return nil
-}
+	// Then the Cisco client's own profile, which only knows servers
+	// With --host-from-profile-file, an installed one stands in for it.
+	// The choice is kept in the flags, so the fallbacks come from it too.
+	if cmd.String("profile-file") == "" && vpnHost == "" && cmd.Bool("host-from-profile-file") {
+		found, err := discoverCiscoHost(cmd.String("profile-host"))
+		if err != nil {
+			return "", "", "", err
+		}
+		choice := found.entry.Name
+		if choice == "" {
+			choice = found.entry.Address
+		}
+		if err := cmd.Set("profile-file", found.path); err != nil {
+			return "", "", "", err
+		}
+		if err := cmd.Set("profile-host", choice); err != nil {
+			return "", "", "", err
+		}
+	}
+	if path := cmd.String("profile-file"); path != "" {
+		entry, err := ciscoHostEntryFor(path, cmd.String("profile-host"))
+		if err != nil {
+			return "", "", "", err
+		}
+		if vpnHost == "" {
+			vpnHost = entry.host()
+		}
+	}
 
-// disconnectAction handles the disconnect command
-func disconnectAction(ctx context.Context, cmd *cli.Command) error {
-	verbose := cmd.Bool("verbose")
+	if username == "" {
+		return "", "", "", fmt.Errorf("--username is required for %s command", cmd.Name)
+	}
+	if vpnHost == "" {
+		return "", "", "", fmt.Errorf("--vpn-host is required for %s command", cmd.Name)
+	}
+	return vpnHost, username, normalizeMethod(method), nil
+}
 
-	vpnExec, err := getVPNExec(cmd)
-	if err != nil {
-		return err
+// targetGiven reports whether any flag names who to connect as, or where
+func targetGiven(cmd *cli.Command) bool {
+	for _, name := range []string{"vpn-host", "username", "uri", "profile", "profile-file", "host-from-profile-file"} {
+		if cmd.IsSet(name) {
+			return true
+		}
 	}
+	return false
+}
 
-	err = disconnectVPN(vpnExec, verbose)
+// connectAction handles the connect command
+func connectAction(ctx context.Context, cmd *cli.Command) error {
+	vpnHost, username, method, err := resolveTarget(cmd)
 	if err != nil {
 		return err
 	}
+	if cmd.Bool("no-mfa") {
+		if cmd.IsSet("method") {
+			return fmt.Errorf("--no-mfa cannot be combined with --method")
+		}
+		method = ""
+	}
+	passcodeCommand := cmd.String("passcode-command")
+	if passcodeCommand != "" {
+		switch {
+		case cmd.IsSet("method"), cmd.Bool("no-mfa"), cmd.Bool("inline-method"):
+			return fmt.Errorf("--passcode-command cannot be combined with --method, --no-mfa or --inline-method")
+		}
+		method = "" // filled in by the command when connecting
+	}
 
-	fmt.Println("VPN disconnection successful")
-	return nil
-}
-
-// statusAction handles the status command
-func statusAction(ctx context.Context, cmd *cli.Command) error {
-	vpnExec, err := getVPNExec(cmd)
+	fallbackHosts, fallbackMethods, err := resolveFallbacks(cmd)
 	if err != nil {
 		return err
 	}
+	if method == "" && passcodeCommand == "" && len(fallbackMethods) > 0 {
+		return fmt.Errorf("--no-mfa cannot be combined with fallback methods")
+	}
+	steps := strategyMatrix(append([]string{vpnHost}, fallbackHosts...), append([]string{method}, fallbackMethods...))
 
-	// FIXME: this code is duplicated
-	// Start spinner for connection process
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	s.Suffix = " Checking VPN Status..."
-	s.Start()
-	defer s.Stop()
+	// Fail before the password and Duo, not at the banner
+	if cmd.Bool("require-banner-ack") && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("--require-banner-ack needs a terminal to acknowledge the server's banner on")
+	}
+	if cmd.Bool("interactive-prompts") {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("--interactive-prompts needs a terminal to answer the client's prompts on")
+		}
+		// These would answer prompts the user is left to answer
+		for _, name := range []string{"method", "no-mfa", "inline-method", "stdin-password", "passcode-command",
+			"second-password", "second-password-file", "show-banner", "require-banner-ack", "trusted-fingerprint"} {
+			if cmd.IsSet(name) {
+				return fmt.Errorf("--interactive-prompts cannot be combined with --%s", name)
+			}
+		}
+	}
+	saveAs := cmd.String("save-profile")
+	if saveAs != "" && !profileNamePattern.MatchString(saveAs) {
+		return fmt.Errorf("invalid profile name %q", saveAs)
+	}
 
-	connected := vpnConnected(vpnExec)
+	fingerprint := cmd.String("trusted-fingerprint")
+	if fingerprint != "" {
+		if err := validateFingerprint(fingerprint); err != nil {
+			return err
+		}
+	}
 
-	s.Stop()
-	if connected {
-		fmt.Println("VPN Connected: Yes")
+	var proxy *url.URL
+	if raw := cmd.String("proxy"); raw != "" {
+		proxy, err = parseProxy(raw, cmd.String("proxy-auth"))
+		if err != nil {
+			return err
+		}
+	} else if cmd.IsSet("proxy-auth") {
+		return fmt.Errorf("--proxy-auth requires --proxy")
+	}
+
+	var onsiteProbe string
+	if cmd.Bool("skip-if-onsite") {
+		cfg, err := loadConfig(cmd.String("config"))
+		if err != nil {
+			return err
+		}
+		onsiteProbe = cfg.OnsiteProbe
+		if cmd.IsSet("onsite-probe") {
+			onsiteProbe = cmd.String("onsite-probe")
+		}
+		if onsiteProbe == "" {
+			return fmt.Errorf("--skip-if-onsite needs --onsite-probe or onsite_probe in the config")
+		}
+	}
+
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	var secondPassword string
+	if path := cmd.String("second-password-file"); path != "" {
+		if cmd.Bool("second-password") {
+			return fmt.Errorf("--second-password cannot be combined with --second-password-file")
+		}
+		secondPassword, err = readSecondPasswordFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Read a piped password before anything else touches stdin
+	var password string
+	if cmd.Bool("stdin-password") {
+		password, err = readStdinPassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Ask for the password up front so a method can be appended to it
+	if cmd.Bool("inline-method") {
+		if !cmd.Bool("takeover") && vpnConnected(vpnExec) {
+			return errAlreadyConnected
+		}
+		input := password
+		if input == "" {
+			input, err = getPassword("Enter VPN password (optionally followed by ,push ,phone ,sms or ,<passcode>): ")
+			if err != nil {
+				return fmt.Errorf("failed to read password: %v", err)
+			}
+		}
+		var inline string
+		password, inline = splitInlineMethod(input)
+		if inline != "" {
+			if cmd.Bool("no-mfa") {
+				return fmt.Errorf("--no-mfa cannot be combined with a method after the password")
+			}
+			slog.Info("using method given with the password", "method", reportedMethod(inline))
+			method = inline
+			steps = strategyMatrix(append([]string{vpnHost}, fallbackHosts...), append([]string{method}, fallbackMethods...))
+		}
+	}
+
+	cfg, err := loadConfig(cmd.String("config"))
+	if err != nil {
+		return err
+	}
+	methodTimeouts, err := parseMethodTimeouts(cfg.MethodTimeouts)
+	if err != nil {
+		return err
+	}
+	statusRules, err := compileStatusRules(cfg.StatusRules)
+	if err != nil {
+		return err
+	}
+	var portalProbe string
+	if !cmd.Bool("no-portal-check") {
+		portalProbe = defaultPortalProbeURL
+		if cfg.PortalProbeURL != "" {
+			portalProbe = cfg.PortalProbeURL
+		}
+	}
+
+	var egressProbe string
+	var egressBefore egressIPs
+	if cmd.Bool("check-egress") {
+		cfg, err := loadConfig(cmd.String("config"))
+		if err != nil {
+			return err
+		}
+		egressProbe = defaultEgressProbeURL
+		if cfg.EgressProbeURL != "" {
+			egressProbe = cfg.EgressProbeURL
+		}
+		if cmd.IsSet("egress-probe-url") {
+			egressProbe = cmd.String("egress-probe-url")
+		}
+		slog.Info("checking egress before connecting", "probe", egressProbe)
+		egressBefore = lookupEgress(ctx, egressProbe)
+	}
+
+	var routesBefore []string
+	if cmd.Bool("route-changes") {
+		routesBefore, err = readRoutes()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The Cisco CLI has no switch to keep its GUI from opening, so close
+	// one the connect brought up
+	if cmd.Bool("no-gui") {
+		guisBefore := runningGUIs()
+		defer closeNewGUIs(guisBefore)
+	}
+
+	result, err := connectWithStrategy(ctx, vpnExec, connectOptions{
+		Username:       username,
+		Password:       password,
+		Verbose:        verbosity >= verbosityChildOutput,
+		Strict:         cmd.Bool("strict"),
+		MFARateLimit:   int(cmd.Int("mfa-rate-limit")),
+		Timeout:        cmd.Duration("timeout"),
+		TimeoutSet:     cmd.IsSet("timeout"),
+		MethodTimeouts: methodTimeouts,
+		ConnectTimeout: cmd.Duration("connect-timeout"),
+		Proxy:          proxy,
+		OnsiteProbe:    onsiteProbe,
+		PortalProbe:    portalProbe,
+		StatusRules:    statusRules,
+		RestartAgent:   cmd.Bool("restart-agent"),
+		Takeover:       cmd.Bool("takeover"),
+
+		ShowBanner:         cmd.Bool("show-banner"),
+		RequireBannerAck:   cmd.Bool("require-banner-ack"),
+		InteractivePrompts: cmd.Bool("interactive-prompts"),
+
+		SecondPassword:    secondPassword,
+		AskSecondPassword: cmd.Bool("second-password"),
+		Retry: retryPolicy{
+			MaxAttempts: int(cmd.Int("max-attempts")),
+			MaxTime:     cmd.Duration("max-retry-time"),
+			BackoffBase: cmd.Duration("backoff"),
+			BackoffCap:  cmd.Duration("backoff-cap"),
+		},
+
+		PasscodeCommand:    passcodeCommand,
+		TrustedFingerprint: fingerprint,
+		ExtraArgs:          cmd.Args().Slice(),
+		CheckClock:         cmd.Bool("check-clock"),
+		ClockReference:     cmd.String("clock-reference"),
+		MaxClockSkew:       cmd.Duration("max-clock-skew"),
+	}, steps)
+	if errors.Is(err, errOnsite) {
+		return nil
+	}
+	if err != nil {
+		if !errors.Is(err, errAlreadyConnected) {
+			saveLastError(err)
+		}
+		return err
+	}
+	clearLastError()
+
+	// Several tunnels can make the address-based guess pick the wrong
+	// interface; one that doesn't exist is only a warning, as the tunnel is up
+	if name := cmd.String("interface-name"); name != "" {
+		if _, err := net.InterfaceByName(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: interface %q not found after connecting; detecting it instead\n", name)
+		} else {
+			result.Interface = name
+		}
+	}
+	writeLease(result)
+	if !cmd.Bool("test") {
+		saveLastConnect(cmd.String("profile"), result, username)
+	}
+
+	if warning := checkTransport(cmd.String("transport"), result.Transport); warning != "" {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+
+	if routesBefore != nil {
+		if routesAfter, err := readRoutes(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			if cmd.IsSet("interface-name") && result.Interface != "" {
+				routesBefore = routesOn(routesBefore, result.Interface, result.ClientAddress)
+				routesAfter = routesOn(routesAfter, result.Interface, result.ClientAddress)
+			}
+			changes := diffRoutes(routesBefore, routesAfter)
+			result.Routes = &changes
+		}
+	}
+
+	// The tunnel is up either way, so a profile that can't be saved is
+	// only a warning
+	if saveAs != "" {
+		if err := saveConnectedProfile(cmd.String("config"), saveAs, result, username); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: profile not saved: %v\n", err)
+		}
+	}
+
+	if cfg, err := loadConfig(cmd.String("config")); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: skipping on_connect hook: %v\n", err)
+	} else if !cmd.Bool("test") {
+		runHook(ctx, cfg.OnConnect, connectHookData(result, username))
+	}
+
+	var egressErr error
+	if egressProbe != "" {
+		slog.Info("checking egress after connecting", "probe", egressProbe)
+		report := compareEgress(egressBefore, lookupEgress(ctx, egressProbe))
+		result.Egress = &report
+		if len(report.Leaks) > 0 {
+			egressErr = fmt.Errorf("egress check failed: %s", strings.Join(report.Leaks, "; "))
+		}
+	}
+
+	// A test connect only proves the credentials work; don't leave the
+	// tunnel up behind it
+	if cmd.Bool("test") {
+		slog.Info("credentials verified; tearing down test session")
+		if err := disconnectVPN(vpnExec, verbosity >= verbosityChildOutput, false, nil); err != nil {
+			return fmt.Errorf("credentials verified, but the test session could not be disconnected: %v", err)
+		}
+		result.Connected = false
+		if format := cmd.String("output"); format != formatText {
+			if err := render(result, format); err != nil {
+				return err
+			}
+			return egressErr
+		}
+		fmt.Printf("Credential test passed: connected to %s as %s and disconnected again\n", result.Host, username)
+		if result.Egress != nil {
+			fmt.Println(result.Egress)
+		}
+		if result.Routes != nil {
+			fmt.Println(result.Routes)
+		}
+		return egressErr
+	}
+
+	if err := render(result, cmd.String("output")); err != nil {
+		return err
+	}
+	return egressErr
+}
+
+// disconnectAction handles the disconnect command
+func disconnectAction(ctx context.Context, cmd *cli.Command) error {
+	verbose := verbosity >= verbosityChildOutput
+
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(cmd.String("config"))
+	if err != nil {
+		return err
+	}
+	confirm := (cmd.Bool("confirm-disconnect") || cfg.ConfirmDisconnect) && !cmd.Bool("yes")
+
+	var beforeHook func()
+	if cfg.OnDisconnect != "" {
+		beforeHook = func() {
+			runHook(ctx, cfg.OnDisconnect, disconnectHookData(vpnExec))
+		}
+	}
+
+	var routesBefore []string
+	if cmd.Bool("route-changes") {
+		routesBefore, err = readRoutes()
+		if err != nil {
+			return err
+		}
+	}
+	// routeChanges diffs against the snapshot, or returns nil without one
+	routeChanges := func() *routeChanges {
+		if routesBefore == nil {
+			return nil
+		}
+		routesAfter, err := readRoutes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return nil
+		}
+		changes := diffRoutes(routesBefore, routesAfter)
+		return &changes
+	}
+
+	if cmd.Bool("all") {
+		result, err := disconnectAll(vpnExec, verbose, confirm, beforeHook)
+		if err != nil {
+			if len(result.Sessions) > 0 {
+				fmt.Fprintln(os.Stderr, result)
+			}
+			return err
+		}
+		result.Routes = routeChanges()
+		return render(result, cmd.String("output"))
+	}
+
+	err = disconnectVPN(vpnExec, verbose, confirm, beforeHook)
+	if err != nil {
+		return err
+	}
+	return render(disconnectResult{vpnStatus: newVPNStatus(false), Routes: routeChanges()}, cmd.String("output"))
+}
+
+// Exit codes of status --quiet; 0 means connected
+const (
+	exitDisconnected = 1
+	exitUnknown      = 2
+)
+
+// exitCodeError ends the run with its exit code and no message, once main
+// has flushed the output
+type exitCodeError int
+
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// statusAction handles the status command
+func statusAction(ctx context.Context, cmd *cli.Command) error {
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	ttl := cmd.Duration("cache-ttl")
+	if status, ok := cachedStatus(vpnExec, ttl, cmd.Bool("quiet")); ok {
+		if cmd.Bool("quiet") {
+			if status.Connected {
+				return nil
+			}
+			return exitCodeError(exitDisconnected)
+		}
+		if status.Connected {
+			status.Lease = readLease()
+		}
+		status.LastError = loadLastError()
+		if path := cmd.String("output-file"); path != "" {
+			return renderFile(path, status, cmd.String("output"))
+		}
+		return render(status, cmd.String("output"))
+	}
+
+	// Quiet mode only sets the exit code, for shell conditionals
+	if cmd.Bool("quiet") {
+		state := vpnState(vpnExec)
+		if ttl > 0 && state != stateUnknown {
+			saveStatusCache(vpnExec, newVPNStatus(state == stateConnected), true)
+		}
+		switch state {
+		case stateConnected:
+			return nil
+		case stateDisconnected:
+			return exitCodeError(exitDisconnected)
+		}
+		return exitCodeError(exitUnknown)
+	}
+
+	s := newSpinner(" Checking VPN Status...")
+	s.Start()
+	defer s.Stop()
+
+	cfg, err := loadConfig(cmd.String("config"))
+	if err != nil {
+		return err
+	}
+	rules, err := compileStatusRules(cfg.StatusRules)
+	if err != nil {
+		return err
+	}
+
+	if assumedState != nil {
+		s.Stop()
+		status := newVPNStatus(*assumedState == stateConnected)
+		status.LastError = loadLastError()
+		return render(status, cmd.String("output"))
+	}
+
+	status := newVPNStatus(false)
+	output, err := runCommand(vpnExec, "status")
+	if err != nil {
+		slog.Debug("VPN status command failed", "error", err)
 	} else {
-		fmt.Println("VPN Connected: No")
+		status.Connected = isConnectedOutput(output)
+		status.Fields = parseStatus(output, rules)
 	}
-	return nil
+	if status.Connected {
+		if stats, err := getStats(vpnExec); err == nil {
+			status.Transport = transportOf(stats.Protocol)
+		}
+		status.Lease = readLease()
+	} else if err == nil {
+		// Disconnected some other way, e.g. from the Cisco app
+		removeLease()
+	}
+	if ttl > 0 && err == nil {
+		saveStatusCache(vpnExec, status, false)
+	}
+	status.LastError = loadLastError()
+
+	s.Stop()
+	if path := cmd.String("output-file"); path != "" {
+		return renderFile(path, status, cmd.String("output"))
+	}
+	return render(status, cmd.String("output"))
+}
+
+// exit flushes the --verbose-log and --tag output, then exits with code
+func exit(code int) {
+	closeChildLog()
+	flushTagged()
+	os.Exit(code)
 }
 
 func main() {
-	// Set default method from environment variable
-	defaultMethod := os.Getenv("VPN_METHOD")
-	if defaultMethod == "" {
-		defaultMethod = "push"
-	}
+	// Put the terminal right before a panic's trace is printed. Panics in
+	// other goroutines still end the process without this.
+	defer func() {
+		if r := recover(); r != nil {
+			stopSpinners()
+			closeChildLog()
+			flushTagged()
+			panic(r)
+		}
+	}()
 
 	cmd := &cli.Command{
 		Name:  "seccli",
 		Usage: "CLI wrapper around Cisco Secure Client",
+		// Allow -vv and -vvv
+		UseShortOptionHandling: true,
+		// main exits, after flushing the output; see exitCodeError
+		ExitErrHandler: func(context.Context, *cli.Command, error) {},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			// Tag only text, so JSON and YAML stay machine-readable
+			if tag := cmd.String("tag"); tag != "" {
+				if cmd.String("output") == formatText {
+					if err := tagOutput(&os.Stdout, tag); err != nil {
+						return ctx, err
+					}
+				}
+				if cmd.String("log-format") == logFormatText {
+					if err := tagOutput(&os.Stderr, tag); err != nil {
+						return ctx, err
+					}
+				}
+			}
+			setupLogging(cmd.String("log-format"))
+			// Commands that need the config report a broken one themselves
+			if cfg, err := loadConfig(cmd.String("config")); err == nil {
+				setStateMarkers(cfg.StateMarkers)
+			} else {
+				slog.Debug("not applying state markers", "error", err)
+			}
+			if path := cmd.String("verbose-log"); path != "" {
+				if err := openChildLog(path); err != nil {
+					return ctx, err
+				}
+			}
+			if d := cmd.Duration("timeout-all"); d > 0 {
+				ctx = startTimeoutAll(ctx, d)
+			}
+			if err := setAssumedState(cmd.Bool("assume-connected"), cmd.Bool("assume-disconnected")); err != nil {
+				return ctx, err
+			}
+			if cmd.Bool("cleanup") {
+				if err := cleanupOrphans(); err != nil {
+					return ctx, err
+				}
+			}
+			return ctx, nil
+		},
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Increase verbosity: -v progress, -vv VPN tool output, -vvv debug details",
+				Config:  cli.BoolConfig{Count: &verbosity},
+			},
+			&cli.BoolFlag{
+				Name:        "ascii",
+				Usage:       "Use an ASCII spinner (auto-detected on terminals without Unicode)",
+				Destination: &asciiSpinner,
+			},
+			&cli.BoolFlag{
+				Name:   "assume-connected",
+				Usage:  "Testing only, with " + testingEnv + "=1: report the VPN as connected without asking the client",
+				Hidden: true,
+			},
+			&cli.BoolFlag{
+				Name:   "assume-disconnected",
+				Usage:  "Testing only, with " + testingEnv + "=1: report the VPN as disconnected without asking the client",
+				Hidden: true,
+			},
+			&cli.BoolFlag{
+				Name:        "no-spinner",
+				Usage:       "Don't show the spinner, keeping all other output",
+				Sources:     cli.EnvVars("SECCLI_NO_SPINNER"),
+				Destination: &noSpinner,
+			},
+			&cli.StringFlag{
+				Name:      "output",
+				Aliases:   []string{"o"},
+				Usage:     "Output format (text, json or yaml)",
+				Value:     formatText,
+				Validator: validateFormat,
+			},
+			&cli.StringFlag{
+				Name:      "log-format",
+				Usage:     "Log format on stderr (text or json)",
+				Value:     logFormatText,
+				Validator: validateLogFormat,
+				Sources:   cli.EnvVars("SECCLI_LOG_FORMAT"),
+			},
+			&cli.StringFlag{
+				Name:  "verbose-log",
+				Usage: "Append the VPN tool's full output to `FILE`, with the password masked, e.g. for bug reports",
+			},
+			&cli.StringFlag{
+				Name:    "tag",
+				Usage:   "Prefix every line of text output and logs with this label, e.g. [seccli]",
+				Sources: cli.EnvVars("SECCLI_TAG"),
+			},
+			&cli.DurationFlag{
+				Name:    "timeout-all",
+				Usage:   "Give up on the whole command after this long, stopping any vpn process (0 = no limit)",
+				Sources: cli.EnvVars("SECCLI_TIMEOUT_ALL"),
+			},
+			&cli.BoolFlag{
+				Name:  "cleanup",
+				Usage: "Stop a vpn process left behind by an interrupted seccli run",
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "Path to the config file",
+				Value:   defaultConfigPath(),
+				Sources: cli.EnvVars("SECCLI_CONFIG"),
+			},
+			&cli.BoolFlag{
+				Name:  "force-exec-recheck",
+				Usage: "Ignore the cached VPN executable location and search again",
+			},
+		},
 		Commands: []*cli.Command{
 			{
-				Name:  "connect",
-				Usage: "Connect to VPN",
+				Name:        "connect",
+				Usage:       "Connect to VPN",
+				UsageText:   connectUsageText,
+				Description: connectDescription,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "username",
-						Aliases:  []string{"u"},
-						Usage:    "Your VPN username",
-						Required: true,
+						Name:    "username",
+						Aliases: []string{"u"},
+						Usage:   "Your VPN username",
+					},
+					&cli.StringFlag{
+						Name:    "vpn-host",
+						Aliases: []string{"h"},
+						Usage:   "VPN URL",
+					},
+					&cli.BoolFlag{
+						Name:  "no-mfa",
+						Usage: "Don't answer a Duo/MFA prompt (for profiles without MFA)",
+					},
+					&cli.StringFlag{
+						Name:    "profile",
+						Aliases: []string{"p"},
+						Usage:   "Saved profile to take connection parameters from",
+					},
+					&cli.BoolFlag{
+						Name:  "last",
+						Usage: "Reuse the host, username and method of the last successful connect (the default with no other target)",
+					},
+					&cli.StringFlag{
+						Name:  "uri",
+						Usage: "Connection URI, e.g. vpn://host?user=netid&method=push",
+					},
+					&cli.StringFlag{
+						Name:  "profile-file",
+						Usage: "Cisco client XML profile to take the server (and backup servers) from",
+					},
+					&cli.BoolFlag{
+						Name:  "host-from-profile-file",
+						Usage: "Without a host, take it from the Cisco XML profiles installed with the client",
 					},
 					&cli.StringFlag{
-						Name:     "vpn-host",
-						Aliases:  []string{"h"},
-						Usage:    "VPN URL",
-						Required: true,
+						Name:  "profile-host",
+						Usage: "Server of the --profile-file (or installed profiles) to use, by name or address (default: the first)",
 					},
 					&cli.StringFlag{
 						Name:    "method",
 						Aliases: []string{"m"},
 						Usage:   "Authentication method",
-						Value:   defaultMethod,
+						Value:   "push",
 					},
 					&cli.StringFlag{
 						Name:  "vpn-exec",
 						Usage: "Path to VPN executable (auto-detected if not provided)",
 					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Refuse to connect if another VPN tunnel is active",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Give up if connecting takes longer than this, including Duo (default: 1m-3m depending on the method; 0 = no limit)",
+					},
+					&cli.DurationFlag{
+						Name:  "connect-timeout",
+						Usage: "Give up if the server hasn't asked for credentials within this time (0 = no limit)",
+						Value: 30 * time.Second,
+					},
+					&cli.BoolFlag{
+						Name:  "route-changes",
+						Usage: "Show the routes the connect added and removed, e.g. to debug split tunneling",
+					},
+					&cli.StringFlag{
+						Name:  "interface-name",
+						Usage: "Tunnel interface the checks after connecting inspect, instead of the one holding the client address",
+					},
+					&cli.BoolFlag{
+						Name:  "show-banner",
+						Usage: "Print the server's banner before accepting it",
+					},
+					&cli.BoolFlag{
+						Name:  "require-banner-ack",
+						Usage: "Show the server's banner and ask before accepting it (needs a terminal)",
+					},
+					&cli.BoolFlag{
+						Name:  "interactive-prompts",
+						Usage: "Show the client's prompts and answer them yourself, for logins the scripted flow can't handle (needs a terminal)",
+					},
+					&cli.BoolFlag{
+						Name:    "no-gui",
+						Usage:   "Close the Cisco GUI if the connect opens it",
+						Sources: cli.EnvVars("SECCLI_NO_GUI"),
+					},
+					&cli.BoolFlag{
+						Name:    "no-portal-check",
+						Usage:   "Don't check for a captive portal (a network sign-in page) before connecting",
+						Sources: cli.EnvVars("SECCLI_NO_PORTAL_CHECK"),
+					},
+					&cli.BoolFlag{
+						Name:  "check-clock",
+						Usage: "Warn before connecting if the system clock is skewed",
+					},
+					&cli.StringFlag{
+						Name:  "clock-reference",
+						Usage: "HTTPS URL whose Date header --check-clock compares against (default: the VPN host)",
+					},
+					&cli.DurationFlag{
+						Name:  "max-clock-skew",
+						Usage: "Clock skew --check-clock tolerates",
+						Value: time.Minute,
+					},
+					&cli.StringFlag{
+						Name:  "trusted-fingerprint",
+						Usage: "Accept an untrusted server certificate only if its SHA-256 fingerprint matches",
+					},
+					&cli.StringFlag{
+						Name:  "proxy",
+						Usage: "Reach the VPN server through this proxy (http://, https:// or socks5:// URL)",
+					},
+					&cli.StringFlag{
+						Name:    "proxy-auth",
+						Usage:   "Proxy credentials as user:password",
+						Sources: cli.EnvVars("SECCLI_PROXY_AUTH"),
+					},
+					&cli.IntFlag{
+						Name:  "mfa-rate-limit",
+						Usage: "Refuse to trigger more than this many Duo prompts per hour (0 = no limit)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "fallback-host",
+						Usage: "Host to try if connecting to --vpn-host fails (repeatable)",
+					},
+					&cli.IntFlag{
+						Name:  "max-attempts",
+						Usage: "Attempts to make in all, going round the host/method combinations again if needed (0 = one per combination)",
+					},
+					&cli.DurationFlag{
+						Name:  "max-retry-time",
+						Usage: "Start no new attempt after this long (0 = no limit)",
+					},
+					&cli.DurationFlag{
+						Name:  "backoff",
+						Usage: "Wait this long after the first failed attempt, doubling after each one (0 = retry right away)",
+					},
+					&cli.DurationFlag{
+						Name:  "backoff-cap",
+						Usage: "Longest wait between attempts (0 = no cap)",
+						Value: time.Minute,
+					},
+					&cli.StringSliceFlag{
+						Name:  "fallback-method",
+						Usage: "Method to retry every host with if --method fails (repeatable)",
+					},
+					&cli.BoolFlag{
+						Name:  "stdin-password",
+						Usage: "Read the password from the first line of stdin instead of prompting",
+					},
+					&cli.BoolFlag{
+						Name:  "second-password",
+						Usage: "Prompt for a second password (e.g. a PIN) for servers that ask for one before Duo",
+					},
+					&cli.StringFlag{
+						Name:  "second-password-file",
+						Usage: "Read the second password from the first line of this file instead of prompting",
+					},
+					&cli.BoolFlag{
+						Name:  "inline-method",
+						Usage: "Accept the Duo method after the password, as password,push or password,123456",
+					},
+					&cli.BoolFlag{
+						Name:  "test",
+						Usage: "Verify the credentials by connecting and disconnecting right away",
+					},
+					&cli.StringFlag{
+						Name:  "passcode-command",
+						Usage: "Run this shell command for the Duo passcode, e.g. from an authenticator CLI (replaces --method)",
+					},
+					&cli.StringFlag{
+						Name:      "transport",
+						Usage:     "Transport to expect: dtls or tls warns if the tunnel came up over the other one (auto, dtls or tls)",
+						Value:     transportAuto,
+						Validator: validateTransport,
+					},
 					&cli.BoolFlag{
-						Name:    "verbose",
-						Aliases: []string{"v"},
-						Usage:   "Show verbose output from VPN tool",
+						Name:  "takeover",
+						Usage: "Disconnect an active session, e.g. another user's on a shared machine, and connect in its place",
+					},
+					&cli.BoolFlag{
+						Name:  "restart-agent",
+						Usage: "If the Cisco agent service isn't responding, restart it (with sudo) and retry once",
+					},
+					&cli.StringFlag{
+						Name:  "save-profile",
+						Usage: "Save the host, username and method to the config as profile `NAME` once connected",
+					},
+					&cli.BoolFlag{
+						Name:  "check-egress",
+						Usage: "After connecting, check that IPv4 and IPv6 traffic leaves through the VPN",
+					},
+					&cli.StringFlag{
+						Name:  "egress-probe-url",
+						Usage: "URL that answers with the caller's public IP, for --check-egress",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-if-onsite",
+						Usage: "Don't connect if the on-site probe shows we're already on the internal network",
+					},
+					&cli.StringFlag{
+						Name:  "onsite-probe",
+						Usage: "Internal-only host:port to dial, or internal DNS name to resolve, for --skip-if-onsite",
 					},
 				},
 				Action: connectAction,
 			},
 			{
-				Name:  "disconnect",
-				Usage: "Disconnect from VPN",
+				Name:        "disconnect",
+				Usage:       "Disconnect from VPN",
+				UsageText:   disconnectUsageText,
+				Description: disconnectDescription,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:  "vpn-exec",
 						Usage: "Path to VPN executable (auto-detected if not provided)",
 					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+					&cli.BoolFlag{
+						Name:  "confirm-disconnect",
+						Usage: "Ask for confirmation before disconnecting (also the confirm_disconnect config policy)",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip the disconnect confirmation",
+					},
 					&cli.BoolFlag{
-						Name:    "verbose",
-						Aliases: []string{"v"},
-						Usage:   "Show verbose output from VPN tool",
+						Name:  "all",
+						Usage: "Disconnect every active session; succeeds if none is active",
+					},
+					&cli.BoolFlag{
+						Name:  "route-changes",
+						Usage: "Show the routes the disconnect added and removed",
 					},
 				},
 				Action: disconnectAction,
 			},
 			{
-				Name:  "status",
-				Usage: "Show VPN connection status",
+				Name:        "status",
+				Usage:       "Show VPN connection status",
+				UsageText:   statusUsageText,
+				Description: statusDescription,
 				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "quiet",
+						Aliases: []string{"q"},
+						Usage:   "Print nothing; exit 0 if connected, 1 if disconnected, 2 if unknown",
+					},
+					&cli.StringFlag{
+						Name:  "output-file",
+						Usage: "Write the status to this file (replaced atomically) instead of stdout",
+					},
+					&cli.DurationFlag{
+						Name:    "cache-ttl",
+						Usage:   "Reuse a status checked less than this long ago, e.g. 2s for a shell prompt (0 = always check)",
+						Sources: cli.EnvVars("SECCLI_STATUS_CACHE_TTL"),
+					},
 					&cli.StringFlag{
 						Name:  "vpn-exec",
 						Usage: "Path to VPN executable (auto-detected if not provided)",
 					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
 				},
 				Action: statusAction,
 			},
+			{
+				Name:        "prompt",
+				Usage:       "Print a VPN indicator for a shell prompt",
+				UsageText:   promptUsageText,
+				Description: promptDescription,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "connected-symbol",
+						Usage: "Printed when connected (\"[vpn] \" by default with --ascii or without Unicode)",
+						Value: promptSymbolUnicode,
+					},
+					&cli.StringFlag{
+						Name:  "disconnected-symbol",
+						Usage: "Printed when disconnected or the state is unknown",
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Usage: "Reuse a status checked less than this long ago",
+						Value: 5 * time.Second,
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Give up on the client after this long and print the disconnected symbol",
+						Value: time.Second,
+					},
+					&cli.StringFlag{
+						Name:  "vpn-exec",
+						Usage: "Path to VPN executable (auto-detected if not provided)",
+					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+				},
+				Action: promptAction,
+			},
+			{
+				Name:        "stats",
+				Usage:       "Show tunnel statistics (protocol, cipher, traffic)",
+				UsageText:   statsUsageText,
+				Description: statsDescription,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "vpn-exec",
+						Usage: "Path to VPN executable (auto-detected if not provided)",
+					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Also list every field the client reported",
+					},
+					&cli.StringFlag{
+						Name:  "output-file",
+						Usage: "Write the statistics to this file (replaced atomically) instead of stdout",
+					},
+				},
+				Action: statsAction,
+			},
+			{
+				Name:        "monitor",
+				Usage:       "Show the tunnel's throughput live",
+				UsageText:   monitorUsageText,
+				Description: monitorDescription,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "vpn-exec",
+						Usage: "Path to VPN executable (auto-detected if not provided)",
+					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "Time between readings",
+						Value: 2 * time.Second,
+					},
+					&cli.IntFlag{
+						Name:  "count",
+						Usage: "Stop after this many readings (0 = until interrupted)",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Stream JSON lines, as with --output json",
+					},
+				},
+				Action: monitorAction,
+			},
+			{
+				Name:  "version",
+				Usage: "Show the seccli version and build details",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print JSON, as with --output json",
+					},
+				},
+				Action: versionAction,
+			},
+			{
+				Name:  "self-update",
+				Usage: "Replace this binary with the latest release",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "check-only",
+						Usage: "Only report whether a newer release is available",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Install the latest release even if it isn't newer, or over a development build",
+					},
+				},
+				Action: selfUpdateAction,
+			},
+			{
+				Name:        "history",
+				Usage:       "List past connects and disconnects",
+				UsageText:   historyUsageText,
+				Description: historyDescription,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "csv",
+						Usage: "Write the events as CSV (time, event, host, duration in seconds)",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only list events since a date (2026-01-31) or for a recent period (36h, 7d)",
+					},
+				},
+				Action: historyAction,
+			},
+			{
+				Name:        "client-logs",
+				Usage:       "Show the Cisco client's own log",
+				UsageText:   clientLogsUsageText,
+				Description: clientLogsDescription,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "follow",
+						Aliases: []string{"f"},
+						Usage:   "Keep printing lines as the client writes them",
+					},
+					&cli.IntFlag{
+						Name:    "lines",
+						Aliases: []string{"n"},
+						Usage:   "Number of lines to show from the end of the log",
+						Value:   50,
+					},
+					&cli.StringFlag{
+						Name:  "level",
+						Usage: "Only show lines at this level or above (debug, info, warning or error)",
+						Value: "debug",
+					},
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "Log file to read instead of the one found in the client's usual locations",
+					},
+				},
+				Action: clientLogsAction,
+			},
+			{
+				Name:  "profile",
+				Usage: "Share and check saved connection profiles",
+				Commands: []*cli.Command{
+					{
+						Name:      "export",
+						Usage:     "Write a profile to a file (secrets are never included)",
+						ArgsUsage: "<name>",
+						UsageText: profileExportUsageText,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "file",
+								Aliases: []string{"f"},
+								Usage:   "File to write (default: stdout)",
+							},
+						},
+						Action: profileExportAction,
+					},
+					{
+						Name:      "import",
+						Usage:     "Add a profile from an exported file",
+						ArgsUsage: "<file>",
+						UsageText: profileImportUsageText,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "Save the profile under a different name",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Overwrite an existing profile with the same name",
+							},
+						},
+						Action: profileImportAction,
+					},
+					{
+						Name:        "validate",
+						Usage:       "Check profiles for problems without connecting",
+						ArgsUsage:   "[<name>...]",
+						UsageText:   profileValidateUsageText,
+						Description: profileValidateDescription,
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "Check every profile in the config",
+							},
+							&cli.StringFlag{
+								Name:  "vpn-exec",
+								Usage: "Path to VPN executable to check (auto-detected if not provided)",
+							},
+							&cli.StringFlag{
+								Name:  "client",
+								Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+							},
+						},
+						Action: profileValidateAction,
+					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Set up the config file",
+				Commands: []*cli.Command{
+					{
+						Name:        "init",
+						Usage:       "Create a profile interactively",
+						UsageText:   configInitUsageText,
+						Description: configInitDescription,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "Profile name (default: cornell)",
+							},
+							&cli.StringFlag{
+								Name:    "vpn-host",
+								Aliases: []string{"h"},
+								Usage:   "VPN URL",
+							},
+							&cli.StringFlag{
+								Name:    "username",
+								Aliases: []string{"u"},
+								Usage:   "Your VPN username",
+							},
+							&cli.StringFlag{
+								Name:    "method",
+								Aliases: []string{"m"},
+								Usage:   "Duo method (default: push)",
+							},
+							&cli.StringFlag{
+								Name:  "vpn-exec",
+								Usage: "Path to VPN executable to save (auto-detected if not provided)",
+							},
+							&cli.BoolFlag{
+								Name:  "no-detect",
+								Usage: "Don't look for the VPN executable",
+							},
+							&cli.BoolFlag{
+								Name:  "non-interactive",
+								Usage: "Ask nothing; take every value from flags or defaults",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Update an existing config without asking",
+							},
+						},
+						Action: configInitAction,
+					},
+				},
+			},
+			{
+				Name:        "ui",
+				Usage:       "Open an interactive dashboard to watch and control the connection",
+				UsageText:   uiUsageText,
+				Description: uiDescription,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "username",
+						Aliases: []string{"u"},
+						Usage:   "Your VPN username",
+					},
+					&cli.StringFlag{
+						Name:    "vpn-host",
+						Aliases: []string{"h"},
+						Usage:   "VPN URL",
+					},
+					&cli.StringFlag{
+						Name:    "profile",
+						Aliases: []string{"p"},
+						Usage:   "Saved profile to take connection parameters from",
+					},
+					&cli.StringFlag{
+						Name:  "uri",
+						Usage: "Connection URI, e.g. vpn://host?user=netid&method=push",
+					},
+					&cli.StringFlag{
+						Name:    "method",
+						Aliases: []string{"m"},
+						Usage:   "Authentication method",
+						Value:   "push",
+					},
+					&cli.StringFlag{
+						Name:  "vpn-exec",
+						Usage: "Path to VPN executable (auto-detected if not provided)",
+					},
+					&cli.StringFlag{
+						Name:  "client",
+						Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+					},
+				},
+				Action: uiAction,
+			},
+			{
+				Name:  "duo",
+				Usage: "Inspect Duo settings offered by the server",
+				Commands: []*cli.Command{
+					{
+						Name:        "devices",
+						Usage:       "List the Duo options the server offers, without connecting",
+						UsageText:   duoDevicesUsageText,
+						Description: duoDevicesDescription,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "username",
+								Aliases: []string{"u"},
+								Usage:   "Your VPN username",
+							},
+							&cli.StringFlag{
+								Name:    "vpn-host",
+								Aliases: []string{"h"},
+								Usage:   "VPN URL",
+							},
+							&cli.StringFlag{
+								Name:    "profile",
+								Aliases: []string{"p"},
+								Usage:   "Saved profile to take connection parameters from",
+							},
+							&cli.StringFlag{
+								Name:  "uri",
+								Usage: "Connection URI, e.g. vpn://host?user=netid",
+							},
+							&cli.StringFlag{
+								Name:  "vpn-exec",
+								Usage: "Path to VPN executable (auto-detected if not provided)",
+							},
+							&cli.StringFlag{
+								Name:  "client",
+								Usage: "Prefer a specific client when auto-detecting (anyconnect or secure-client)",
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: "Give up if the Duo prompt hasn't appeared within this time (0 = no limit)",
+								Value: time.Minute,
+							},
+							&cli.StringFlag{
+								Name:  "trusted-fingerprint",
+								Usage: "Accept an untrusted server certificate only if its SHA-256 fingerprint matches",
+							},
+							&cli.BoolFlag{
+								Name:  "show-banner",
+								Usage: "Print the server's banner before accepting it",
+							},
+						},
+						Action: duoDevicesAction,
+					},
+				},
+			},
 		},
 	}
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
+	err := cmd.Run(context.Background(), os.Args)
+	code := 0
+	var exitCode exitCodeError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitCode) && timedOut() == nil:
+		code = int(exitCode)
+	default:
+		code = 1
+		// Report the deadline rather than whatever it interrupted
+		if cause := timedOut(); cause != nil {
+			err, code = cause, exitTimeout
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
 	}
+	exit(code)
 }
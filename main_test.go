@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+// runResolveTarget runs resolveTarget over args with the connect command's
+// target flags
+func runResolveTarget(t *testing.T, configPath string, args ...string) string {
+	t.Helper()
+	var method string
+	cmd := &cli.Command{
+		Name: "resolve",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "config", Value: configPath},
+			&cli.StringFlag{Name: "username", Aliases: []string{"u"}},
+			&cli.StringFlag{Name: "vpn-host", Aliases: []string{"h"}},
+			&cli.StringFlag{Name: "method", Aliases: []string{"m"}, Value: "push"},
+			&cli.StringFlag{Name: "uri"},
+			&cli.StringFlag{Name: "profile", Aliases: []string{"p"}},
+			&cli.StringFlag{Name: "profile-file"},
+			&cli.StringFlag{Name: "profile-host"},
+			&cli.BoolFlag{Name: "host-from-profile-file"},
+			&cli.BoolFlag{Name: "last"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			_, _, method, err = resolveTarget(cmd)
+			return err
+		},
+	}
+	if err := cmd.Run(context.Background(), append([]string{"resolve"}, args...)); err != nil {
+		t.Fatalf("resolveTarget(%v): %v", args, err)
+	}
+	return method
+}
+
+func TestResolveTargetMethodPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SECCLI_STATE_DIR", dir)
+	t.Setenv(systemConfigEnv, filepath.Join(dir, "no-system-config.json"))
+
+	writeConfig := func(name string, cfg config) string {
+		path := filepath.Join(dir, name)
+		data, _ := json.Marshal(cfg)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	plain := writeConfig("plain.json", config{Profiles: map[string]profile{
+		"sms":       {Host: "vpn.example.edu", Username: "me", Method: "sms"},
+		"no-method": {Host: "vpn.example.edu", Username: "me"},
+	}})
+	perOS := writeConfig("os.json", config{OSMethods: map[string]string{runtime.GOOS: "phone2"}})
+
+	// A recorded connect, reused by --last
+	saveLastConnect("", connectResult{Host: "vpn.example.edu", Method: "phone3"}, "me")
+
+	target := []string{"-h", "vpn.example.edu", "-u", "me"}
+	tests := []struct {
+		name   string
+		env    string // VPN_METHOD
+		config string
+		args   []string
+		want   string
+	}{
+		{"built-in default", "", plain, target, "push"},
+		{"VPN_METHOD over the default", "phone", plain, target, "phone"},
+		{"os_methods over the default", "", perOS, target, "phone2"},
+		{"VPN_METHOD over os_methods", "sms", perOS, target, "sms"},
+		{"profile over VPN_METHOD", "phone", plain, []string{"-p", "sms"}, "sms"},
+		{"profile without a method leaves VPN_METHOD", "phone", plain, []string{"-p", "no-method"}, "phone"},
+		{"flag over profile", "phone", plain, []string{"-p", "sms", "-m", "push2"}, "push2"},
+		{"flag over VPN_METHOD", "sms", plain, append([]string{"-m", "phone"}, target...), "phone"},
+		{"URI over profile", "", plain, []string{"-p", "sms", "--uri", "vpn://vpn.example.edu?user=me&method=phone"}, "phone"},
+		{"flag over URI", "", plain, []string{"-m", "sms", "--uri", "vpn://vpn.example.edu?user=me&method=phone"}, "sms"},
+		{"last connect over VPN_METHOD", "sms", plain, []string{"--last"}, "phone3"},
+		{"profile over last connect", "", plain, []string{"--last", "-p", "sms"}, "sms"},
+		{"flag normalized", "", plain, append([]string{"-m", " Call "}, target...), "phone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VPN_METHOD", tt.env)
+			if got := runResolveTarget(t, tt.config, tt.args...); got != tt.want {
+				t.Errorf("method = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitInlineMethod(t *testing.T) {
+	tests := []struct {
+		input, password, method string
+	}{
+		{"hunter2,push", "hunter2", "push"},
+		{"hunter2,push2", "hunter2", "push2"},
+		{"hunter2, Phone ", "hunter2", "phone"},
+		{"hunter2,SMS3", "hunter2", "sms3"},
+		{"hunter2,call", "hunter2", "phone"},
+		{"hunter2,Call2", "hunter2", "phone2"},
+		{"hunter2,text", "hunter2", "sms"},
+		{"hunter2,123456", "hunter2", "123456"},
+		{"hunter2,123456789", "hunter2", "123456789"},
+		{"a,b,push", "a,b", "push"},
+		// Not a method or passcode, so part of the password
+		{"hunter2", "hunter2", ""},
+		{"hunter2,", "hunter2,", ""},
+		{"hunter2,12345", "hunter2,12345", ""},
+		{"hunter2,1234567890", "hunter2,1234567890", ""},
+		{"hunter2,push22", "hunter2,push22", ""},
+		{"hunter2,pushy", "hunter2,pushy", ""},
+		{"hunter2,passcode", "hunter2,passcode", ""},
+		{"push,hunter2", "push,hunter2", ""},
+	}
+	for _, tt := range tests {
+		password, method := splitInlineMethod(tt.input)
+		if password != tt.password || method != tt.method {
+			t.Errorf("splitInlineMethod(%q) = %q, %q; want %q, %q", tt.input, password, method, tt.password, tt.method)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// monitorSample is one throughput reading: the counters from "vpn stats"
+// and the rates since the reading before
+type monitorSample struct {
+	Time          time.Time `json:"time"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	UpRate        float64   `json:"up_bytes_per_second"`
+	DownRate      float64   `json:"down_bytes_per_second"`
+}
+
+// String formats the sample as one compact line
+func (m monitorSample) String() string {
+	return fmt.Sprintf("%s  up %s/s  down %s/s  (sent %s, received %s)", m.Time.Local().Format(time.TimeOnly),
+		formatBytes(m.UpRate), formatBytes(m.DownRate), formatBytes(float64(m.BytesSent)), formatBytes(float64(m.BytesReceived)))
+}
+
+// formatBytes renders a byte count with a decimal unit, e.g. "12.3 KB"
+func formatBytes(n float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// rate is how fast a counter grew between two readings, in bytes per
+// second. A counter that went down was reset by a new session, and counts
+// as idle.
+func rate(before, after int64, elapsed time.Duration) float64 {
+	if after < before || elapsed <= 0 {
+		return 0
+	}
+	return math.Round(float64(after-before) / elapsed.Seconds())
+}
+
+// sampleStats reads the counters, failing once the tunnel is down
+func sampleStats(vpnExec string) (vpnStats, error) {
+	stats, err := getStats(vpnExec)
+	if err == nil && len(stats.Fields) > 0 {
+		return stats, nil
+	}
+	if !vpnConnected(vpnExec) {
+		return vpnStats{}, fmt.Errorf("VPN is not connected.")
+	}
+	return stats, err
+}
+
+// printSample writes one sample. JSON is one object per line, and YAML one
+// document per sample, so either can be read as a stream.
+func printSample(m monitorSample, format string) error {
+	switch format {
+	case formatJSON:
+		data, err := json.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %v", err)
+		}
+		_, err = fmt.Println(string(data))
+		return err
+	case formatYAML:
+		fmt.Println("---")
+	}
+	return renderTo(os.Stdout, m, format)
+}
+
+// monitorAction handles the monitor command
+func monitorAction(ctx context.Context, cmd *cli.Command) error {
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+	interval := cmd.Duration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	count := int(cmd.Int("count"))
+	format := cmd.String("output")
+	if cmd.Bool("json") {
+		format = formatJSON
+	}
+
+	prev, err := sampleStats(vpnExec)
+	if err != nil {
+		return err
+	}
+	prevTime := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for printed := 0; count <= 0 || printed < count; printed++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		stats, err := sampleStats(vpnExec)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		elapsed := now.Sub(prevTime)
+		sample := monitorSample{
+			Time:          now,
+			BytesSent:     stats.BytesSent,
+			BytesReceived: stats.BytesReceived,
+			UpRate:        rate(prev.BytesSent, stats.BytesSent, elapsed),
+			DownRate:      rate(prev.BytesReceived, stats.BytesReceived, elapsed),
+		}
+		if err := printSample(sample, format); err != nil {
+			return err
+		}
+		prev, prevTime = stats, now
+	}
+	return nil
+}
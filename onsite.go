@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// onsiteProbeTimeout bounds the on-site check, so off-campus connects aren't
+// slowed down noticeably
+const onsiteProbeTimeout = 2 * time.Second
+
+// errOnsite is returned by connectVPN when --skip-if-onsite found the
+// internal network already reachable
+var errOnsite = errors.New("already on the internal network")
+
+// onsiteReachable checks the on-site probe. A host:port probe must accept a
+// TCP connection; a bare name must resolve, which only works for names
+// published on internal DNS alone.
+func onsiteReachable(ctx context.Context, probe string) bool {
+	ctx, cancel := context.WithTimeout(ctx, onsiteProbeTimeout)
+	defer cancel()
+
+	if _, _, err := net.SplitHostPort(probe); err == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", probe)
+		if err != nil {
+			slog.Debug("on-site probe unreachable", "probe", probe, "error", err)
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, probe)
+	if err != nil {
+		slog.Debug("on-site probe did not resolve", "probe", probe, "error", err)
+		return false
+	}
+	slog.Debug("on-site probe resolved", "probe", probe, "addrs", addrs)
+	return true
+}
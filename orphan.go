@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// childPIDFile records the "vpn -s" process spawned by a running seccli
+const childPIDFile = "vpn-child.json"
+
+// childRecord identifies a spawned VPN process and the seccli that owns it
+type childRecord struct {
+	PID       int    `json:"pid"`
+	Exec      string `json:"exec"`
+	ParentPID int    `json:"parent_pid"`
+}
+
+// runTracked runs cmd in its own process group (see startInGroup),
+// recording its PID while it runs so that a later --cleanup can reap it if
+// this process dies first
+func runTracked(cmd *exec.Cmd) error {
+	release, err := startInGroup(cmd)
+	if err != nil {
+		return explainLaunch(cmd.Path, err)
+	}
+	defer release()
+
+	path, err := statePath(childPIDFile)
+	if err == nil {
+		record := childRecord{PID: cmd.Process.Pid, Exec: cmd.Path, ParentPID: os.Getpid()}
+		if data, err := json.Marshal(record); err == nil {
+			err = os.WriteFile(path, data, 0600)
+		}
+		if err != nil {
+			slog.Debug("failed to record VPN process", "error", err)
+		}
+		defer os.Remove(path)
+	}
+
+	return cmd.Wait()
+}
+
+// cleanupOrphans kills a "vpn -s" process left behind by a seccli run that
+// was killed mid-connect. Only the recorded process is considered, and only
+// if it still runs the recorded executable, so Cisco UI processes and
+// unrelated vpn sessions are never touched.
+func cleanupOrphans() error {
+	path, err := statePath(childPIDFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var record childRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.PID <= 0 {
+		slog.Debug("removing unreadable VPN process record", "path", path)
+		return os.Remove(path)
+	}
+
+	// The owning seccli is still running, so the child isn't orphaned
+	if self, err := os.Executable(); err == nil && processRuns(record.ParentPID, self) {
+		slog.Debug("VPN process is still owned by a running seccli", "pid", record.PID, "parent", record.ParentPID)
+		return nil
+	}
+
+	if processRuns(record.PID, record.Exec) {
+		process, err := os.FindProcess(record.PID)
+		if err == nil {
+			err = process.Kill()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stop orphaned VPN process %d: %v", record.PID, err)
+		}
+		fmt.Fprintf(os.Stderr, "Stopped orphaned VPN process (PID %d)\n", record.PID)
+	}
+
+	return os.Remove(path)
+}
+
+// processRuns checks if pid is alive and running the executable at path.
+// Matching the executable guards against the PID having been reused.
+func processRuns(pid int, path string) bool {
+	if pid <= 0 {
+		return false
+	}
+	running, err := processExecutable(pid)
+	if err != nil || running == "" {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Base(running), filepath.Base(path))
+	}
+	return filepath.Base(running) == filepath.Base(path)
+}
+
+// processExecutable returns the executable (or image name) of a process
+func processExecutable(pid int) (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	case "windows":
+		output, err := runCommand("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH")
+		if err != nil {
+			return "", err
+		}
+		// "vpncli.exe","1234","Console","1","12,345 K"
+		fields := strings.Split(output, ",")
+		if len(fields) < 2 || strings.Trim(fields[1], `"`) != strconv.Itoa(pid) {
+			return "", nil
+		}
+		return strings.Trim(fields[0], `"`), nil
+	default:
+		return runCommand("ps", "-p", strconv.Itoa(pid), "-o", "comm=")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats accepted by --output
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// validateFormat checks an --output value
+func validateFormat(format string) error {
+	switch format {
+	case formatText, formatJSON, formatYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid --output %q (expected %s, %s or %s)", format, formatText, formatJSON, formatYAML)
+}
+
+// render writes v to stdout in the given format. Text output uses v's
+// String method, so every rendered type should implement fmt.Stringer.
+func render(v any, format string) error {
+	return renderTo(os.Stdout, v, format)
+}
+
+// renderFile writes v to path in the given format. It writes a temporary
+// file next to path and renames it over path, so a reader polling the file
+// never sees it half written.
+func renderFile(path string, v any, format string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if err := renderTo(tmp, v, format); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	// CreateTemp makes the file private; a snapshot is meant to be read by
+	// other processes
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// renderTo writes v to w in the given format
+func renderTo(w io.Writer, v any, format string) error {
+	switch format {
+	case formatJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case formatYAML:
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, data)
+		return err
+	default:
+		_, err := fmt.Fprintln(w, v)
+		return err
+	}
+}
+
+// toYAML encodes v as YAML. It goes through JSON so that the json struct
+// tags define the field names for both formats.
+func toYAML(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %v", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %v", err)
+	}
+
+	var b strings.Builder
+	writeYAML(&b, generic, 0)
+	return b.String(), nil
+}
+
+// writeYAML writes a decoded JSON value as a YAML block at the given indent
+func writeYAML(b *strings.Builder, v any, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := v[k]
+			if isYAMLScalar(child) {
+				fmt.Fprintf(b, "%s%s: %s\n", pad, yamlString(k), yamlScalar(child))
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, yamlString(k))
+			writeYAML(b, child, indent+2)
+		}
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range v {
+			if isYAMLScalar(item) {
+				fmt.Fprintf(b, "%s- %s\n", pad, yamlScalar(item))
+				continue
+			}
+			// Render the item one level deeper, then hang it off the dash
+			var nested strings.Builder
+			writeYAML(&nested, item, indent+2)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	default:
+		b.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+// isYAMLScalar reports whether a decoded JSON value fits on one line
+func isYAMLScalar(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return true
+}
+
+// yamlScalar formats a decoded JSON scalar (or empty collection)
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	case map[string]any:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlString quotes a string when YAML would otherwise misread it
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, ":#\n\t\"'\\") ||
+		strings.ContainsAny(s[:1], "-?[]{},&*!|>%@`") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
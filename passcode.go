@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// passcodeCommandTimeout bounds a --passcode-command run
+const passcodeCommandTimeout = 10 * time.Second
+
+// passcodePattern is what a Duo passcode looks like: 6 digits from an app or
+// token, up to 9 for bypass codes
+var passcodePattern = regexp.MustCompile(`^[0-9]{6,9}$`)
+
+// runPasscodeCommand runs a --passcode-command and returns the passcode it
+// printed. The passcode never appears in logs or errors.
+func runPasscodeCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, passcodeCommandTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	slog.Info("running passcode command", "command", command)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("passcode command failed: %v", err)
+	}
+	passcode := strings.TrimSpace(stdout.String())
+	if !passcodePattern.MatchString(passcode) {
+		return "", fmt.Errorf("passcode command printed %d characters, not a Duo passcode of 6 to 9 digits", len(passcode))
+	}
+	return passcode, nil
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// readPassword reads a line from the terminal without echoing it
+func readPassword() (string, error) {
+	password, err := term.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// readPassword reads a line from the console without echoing it.
+// term.ReadPassword on the process's stdin handle sometimes returns only
+// part of the line under ConPTY terminals, so this opens the console input
+// buffer itself and reads until the newline.
+func readPassword() (string, error) {
+	conin, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the console: %v", err)
+	}
+	defer conin.Close()
+	handle := windows.Handle(conin.Fd())
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return "", fmt.Errorf("failed to read the console mode: %v", err)
+	}
+	noEcho := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT | windows.ENABLE_PROCESSED_INPUT
+	if err := windows.SetConsoleMode(handle, noEcho); err != nil {
+		return "", fmt.Errorf("failed to turn off echo: %v", err)
+	}
+	defer windows.SetConsoleMode(handle, mode)
+
+	// ReadConsole may hand back the line in pieces; UTF-16 keeps
+	// non-ASCII passwords intact whatever the console code page
+	var line []uint16
+	buf := make([]uint16, 256)
+	for {
+		var n uint32
+		if err := windows.ReadConsole(handle, &buf[0], uint32(len(buf)), &n, nil); err != nil {
+			return "", fmt.Errorf("failed to read from the console: %v", err)
+		}
+		if n == 0 {
+			return "", fmt.Errorf("failed to read from the console: input closed")
+		}
+		line = append(line, buf[:n]...)
+		if text := string(utf16.Decode(line)); strings.Contains(text, "\n") {
+			text, _, _ = strings.Cut(text, "\n")
+			return strings.TrimSuffix(text, "\r"), nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// handshakeMarkers appear once the VPN server has answered and asks for
+// credentials, which ends the handshake phase of a connect
+var handshakeMarkers = []string{"username:", "password:"}
+
+// phaseWatcher is an io.Writer over the client's output that signals when
+// any of its markers is seen
+type phaseWatcher struct {
+	markers []string
+	tail    string // lowercased end of the output, for markers split across writes
+	done    chan struct{}
+	once    sync.Once
+}
+
+// newPhaseWatcher creates a watcher for the given lowercase markers
+func newPhaseWatcher(markers []string) *phaseWatcher {
+	return &phaseWatcher{markers: markers, done: make(chan struct{})}
+}
+
+// Write scans output for the markers
+func (w *phaseWatcher) Write(p []byte) (int, error) {
+	if w.reached() {
+		return len(p), nil
+	}
+	w.tail += strings.ToLower(string(p))
+	for _, marker := range w.markers {
+		if strings.Contains(w.tail, marker) {
+			w.once.Do(func() { close(w.done) })
+			return len(p), nil
+		}
+	}
+	if len(w.tail) > 256 {
+		w.tail = w.tail[len(w.tail)-256:]
+	}
+	return len(p), nil
+}
+
+// reached reports whether a marker has been seen
+func (w *phaseWatcher) reached() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
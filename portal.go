@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultPortalProbeURL answers 204 No Content on an open network; a
+// captive portal redirects it or serves its sign-in page instead
+const defaultPortalProbeURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// portalCheckTimeout bounds the captive portal preflight
+const portalCheckTimeout = 3 * time.Second
+
+// detectCaptivePortal fetches the probe over plain HTTP, without following
+// redirects, and describes the portal in the way if the answer isn't the
+// expected empty 204. Anything else, including no network at all, is only
+// logged and left for the connect to report.
+func detectCaptivePortal(ctx context.Context, probe string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, portalCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe, nil)
+	if err != nil {
+		slog.Debug("skipping captive portal check", "probe", probe, "error", err)
+		return "", false
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Debug("skipping captive portal check", "probe", probe, "error", err)
+		return "", false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location := resp.Header.Get("Location")
+		if u, err := url.Parse(location); err == nil && u.Host != "" {
+			location = u.Host
+		}
+		return fmt.Sprintf("it redirected %s to %s", probe, location), true
+	case resp.StatusCode == http.StatusOK && len(body) > 0:
+		return fmt.Sprintf("it answered %s with a page of its own", probe), true
+	case resp.StatusCode != http.StatusNoContent:
+		slog.Debug("unexpected captive portal probe answer", "probe", probe, "status", resp.Status)
+	}
+	return "", false
+}
+
+// checkCaptivePortal fails the connect with advice when a captive portal,
+// as on hotel and airport Wi-Fi, would block it
+func checkCaptivePortal(ctx context.Context, probe string) error {
+	detail, found := detectCaptivePortal(ctx, probe)
+	if !found {
+		return nil
+	}
+	return knownFailure(fmt.Sprintf("this network seems to need you to sign in first (%s); open a web page in a browser "+
+		"to get the network's sign-in page, then connect again (or skip this check with --no-portal-check)", detail))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// tunnelRule maps an interface naming convention to the VPN tool that uses it
+type tunnelRule struct {
+	match  string
+	prefix bool // match as a name prefix rather than a substring
+	tool   string
+}
+
+// tunnelRules are checked in order, so more specific names come first
+var tunnelRules = []tunnelRule{
+	{"cscotun", true, "Cisco AnyConnect/Secure Client"},
+	{"wg", true, "WireGuard"},
+	{"wireguard", false, "WireGuard"},
+	{"tailscale", false, "Tailscale"},
+	{"zt", true, "ZeroTier"},
+	{"zerotier", false, "ZeroTier"},
+	{"openvpn", false, "OpenVPN"},
+	{"tap-windows", false, "OpenVPN"},
+	{"wintun", false, "WireGuard/OpenVPN"},
+	{"tap", true, "TAP tunnel (e.g. OpenVPN)"},
+	{"utun", true, "tunnel"},
+	{"tun", true, "tunnel"},
+	{"ppp", true, "PPP (e.g. L2TP/PPTP)"},
+	{"ipsec", true, "IPsec"},
+}
+
+// tunnelInterface is an active network interface that looks like a VPN tunnel
+type tunnelInterface struct {
+	Name string
+	Tool string
+}
+
+// findActiveTunnels lists interfaces that are up, carry a routable address,
+// and are named like a VPN tunnel
+func findActiveTunnels() ([]tunnelInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %v", err)
+	}
+
+	var tunnels []tunnelInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		tool := tunnelTool(iface.Name)
+		if tool == "" {
+			continue
+		}
+		// macOS keeps several idle utun interfaces with only link-local
+		// addresses, so require an address that could carry traffic
+		if !hasRoutableAddr(iface) {
+			continue
+		}
+		tunnels = append(tunnels, tunnelInterface{Name: iface.Name, Tool: tool})
+	}
+	return tunnels, nil
+}
+
+// tunnelTool returns the VPN tool an interface name belongs to, or "" if the
+// name doesn't look like a tunnel
+func tunnelTool(name string) string {
+	name = strings.ToLower(name)
+	for _, rule := range tunnelRules {
+		if rule.prefix && strings.HasPrefix(name, rule.match) {
+			return rule.tool
+		}
+		if !rule.prefix && strings.Contains(name, rule.match) {
+			return rule.tool
+		}
+	}
+	return ""
+}
+
+// hasRoutableAddr checks if an interface has a non-link-local unicast address
+func hasRoutableAddr(iface net.Interface) bool {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConflictingTunnels warns about other active VPN tunnels before
+// connecting, or fails if strict is set
+func checkConflictingTunnels(strict bool) error {
+	tunnels, err := findActiveTunnels()
+	if err != nil {
+		return err
+	}
+	if len(tunnels) == 0 {
+		return nil
+	}
+
+	var found []string
+	for _, t := range tunnels {
+		found = append(found, fmt.Sprintf("%s (%s)", t.Name, t.Tool))
+	}
+	msg := fmt.Sprintf("another VPN appears to be active: %s", strings.Join(found, ", "))
+
+	if strict {
+		return fmt.Errorf("%s; disconnect it first or omit --strict", msg)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s; routing may conflict\n", msg)
+	return nil
+}
+
+// systemExtension is one entry of "systemextensionsctl list"
+type systemExtension struct {
+	BundleID string
+	Name     string
+	State    string // e.g. "activated enabled" or "activated waiting for user"
+}
+
+// parseSystemExtensions reads the extension lines of "systemextensionsctl
+// list", which are tab-separated with the state in brackets at the end
+func parseSystemExtensions(output string) []systemExtension {
+	var exts []systemExtension
+	for _, line := range strings.Split(output, "\n") {
+		// Blank enabled/active columns leave leading tabs, so don't trim
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 6 {
+			continue
+		}
+		state := fields[len(fields)-1]
+		if !strings.HasPrefix(state, "[") || !strings.HasSuffix(state, "]") || state == "[state]" {
+			continue // the column header
+		}
+		bundleID, _, _ := strings.Cut(fields[3], " (")
+		exts = append(exts, systemExtension{
+			BundleID: bundleID,
+			Name:     fields[4],
+			State:    strings.Trim(state, "[]"),
+		})
+	}
+	return exts
+}
+
+// errSystemExtensionBlocked explains a Cisco system extension macOS hasn't
+// been allowed to load. Connecting then fails without a useful message.
+var errSystemExtensionBlocked = knownFailure("macOS is blocking the Cisco system extension, so the VPN can't start; " +
+	"open System Settings > Privacy & Security and click Allow next to the Cisco system software message " +
+	"(on macOS 15 and later: General > Login Items & Extensions > Network Extensions, and turn on Cisco Secure Client), " +
+	"then connect again")
+
+// checkSystemExtension fails if macOS lists a Cisco system extension that
+// is waiting for the user's approval. Anything else, including an older
+// client without extensions, passes.
+func checkSystemExtension() error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	output, err := runCommand("systemextensionsctl", "list")
+	if err != nil {
+		slog.Debug("skipping system extension check", "error", err)
+		return nil
+	}
+	for _, ext := range parseSystemExtensions(output) {
+		if !strings.HasPrefix(ext.BundleID, "com.cisco.") {
+			continue
+		}
+		slog.Debug("found Cisco system extension", "bundle", ext.BundleID, "state", ext.State)
+		// Superseded versions linger as "terminated ...", which is fine
+		if strings.Contains(ext.State, "waiting for user") {
+			return errSystemExtensionBlocked
+		}
+	}
+	return nil
+}
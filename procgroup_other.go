@@ -0,0 +1,54 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// startInGroup starts cmd in a process group of its own, so that cancelling
+// its context kills helpers the client spawned along with it. Outside the
+// terminal's process group the client no longer gets Ctrl-C itself, so
+// until the returned func is called, seccli being interrupted or terminated
+// kills the group too; the signal is then raised again to end seccli as it
+// would have.
+func startInGroup(cmd *exec.Cmd) (func(), error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	// Signals seccli ignores, like SIGINT in a background job, stay ignored
+	signals := make(chan os.Signal, 1)
+	for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP} {
+		if !signal.Ignored(sig) {
+			signal.Notify(signals, sig)
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		signal.Stop(signals)
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			stopSpinners()
+			signal.Reset(sig)
+			syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}, nil
+}
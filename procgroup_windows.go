@@ -0,0 +1,72 @@
+//go:build windows
+
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// newKillOnCloseJob creates a job object whose processes all end when its
+// last handle is closed, including when seccli exits however it does
+func newKillOnCloseJob() (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return 0, err
+	}
+	return job, nil
+}
+
+// assignToJob puts a running process in a job object
+func assignToJob(job windows.Handle, pid int) error {
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(process)
+	return windows.AssignProcessToJobObject(job, process)
+}
+
+// startInGroup starts cmd in a job object, so that cancelling its context
+// ends helpers the client spawned along with it. Calling the returned func,
+// or seccli exiting, closes the job and ends anything still in it. If the
+// job can't be set up, the client runs without one.
+func startInGroup(cmd *exec.Cmd) (func(), error) {
+	job, err := newKillOnCloseJob()
+	if err != nil {
+		slog.Debug("failed to create job object", "error", err)
+		return func() {}, cmd.Start()
+	}
+
+	var assigned atomic.Bool
+	cmd.Cancel = func() error {
+		if assigned.Load() {
+			return windows.TerminateJobObject(job, 1)
+		}
+		return cmd.Process.Kill()
+	}
+	if err := cmd.Start(); err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+	if err := assignToJob(job, cmd.Process.Pid); err != nil {
+		slog.Debug("failed to assign VPN process to job object", "error", err)
+	} else {
+		assigned.Store(true)
+	}
+	return func() { windows.CloseHandle(job) }, nil
+}
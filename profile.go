@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// profileExportAction handles the profile export command
+func profileExportAction(ctx context.Context, cmd *cli.Command) error {
+	name := cmd.Args().First()
+	if name == "" {
+		return fmt.Errorf("profile export requires a profile name")
+	}
+
+	p, err := lookupProfile(cmd.String("config"), name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(exportedProfile{Name: name, profile: p}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %v", err)
+	}
+	data = append(data, '\n')
+
+	file := cmd.String("file")
+	if file == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return fmt.Errorf("failed to write profile: %v", err)
+	}
+	fmt.Printf("Exported profile %q to %s\n", name, file)
+	return nil
+}
+
+// profileImportAction handles the profile import command
+func profileImportAction(ctx context.Context, cmd *cli.Command) error {
+	file := cmd.Args().First()
+	if file == "" {
+		return fmt.Errorf("profile import requires a file")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %v", err)
+	}
+
+	var imported exportedProfile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&imported); err != nil {
+		return fmt.Errorf("failed to parse profile %s: %v", file, err)
+	}
+
+	name := imported.Name
+	if cmd.IsSet("name") {
+		name = cmd.String("name")
+	}
+	if err := validateProfile(name, imported.profile); err != nil {
+		return err
+	}
+
+	configPath := cmd.String("config")
+	if err := checkUnlocked("profiles"); err != nil {
+		return err
+	}
+	cfg, err := loadUserConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if _, exists := cfg.Profiles[name]; exists && !cmd.Bool("force") {
+		return fmt.Errorf("profile %q already exists; use --force to overwrite it", name)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	cfg.Profiles[name] = imported.profile
+
+	if err := saveConfig(configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Imported profile %q\n", name)
+	return nil
+}
+
+// saveConnectedProfile writes the parameters of a working connection to the
+// config as a named profile, asking before it replaces an existing one
+func saveConnectedProfile(configPath, name string, result connectResult, username string) error {
+	p := profile{Host: result.Host, Username: username}
+	switch result.Method {
+	case "none":
+	case "passcode":
+		// A passcode only works once
+		fmt.Fprintf(os.Stderr, "Warning: not saving the one-time passcode as profile %q's method\n", name)
+	default:
+		p.Method = result.Method
+	}
+	if err := validateProfile(name, p); err != nil {
+		return err
+	}
+
+	if err := checkUnlocked("profiles"); err != nil {
+		return err
+	}
+	cfg, err := loadUserConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if old, exists := cfg.Profiles[name]; exists {
+		if old.Host == p.Host && old.Username == p.Username && old.Method == p.Method {
+			return nil // already saved, fallbacks and all
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("profile %q already exists; not overwriting it without confirmation", name)
+		}
+		ok, err := askYesNo(fmt.Sprintf("Profile %q already exists (host %s); overwrite it? [y/N]: ", name, old.Host))
+		if err != nil {
+			return fmt.Errorf("failed to read answer: %v", err)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Kept the existing profile %q\n", name)
+			return nil
+		}
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	cfg.Profiles[name] = p
+
+	if err := saveConfig(configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved profile %q\n", name)
+	return nil
+}
+
+// hostLookupTimeout bounds the DNS lookup of each profile host
+const hostLookupTimeout = 5 * time.Second
+
+// profileCheck is the result of validating one profile
+type profileCheck struct {
+	Name     string   `json:"name"`
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// profileChecks are printed as one pass/fail line per profile
+type profileChecks []profileCheck
+
+// String formats the checks for text output
+func (c profileChecks) String() string {
+	var lines []string
+	for _, check := range c {
+		if check.OK {
+			lines = append(lines, "PASS  "+check.Name)
+			continue
+		}
+		lines = append(lines, "FAIL  "+check.Name)
+		for _, problem := range check.Problems {
+			lines = append(lines, "        "+problem)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// methodProblem describes what is wrong with a profile method, if anything
+func methodProblem(method string) string {
+	method = normalizeMethod(method)
+	switch methodKind(method) {
+	case "none", "push", "phone", "sms":
+		return ""
+	case "passcode":
+		return fmt.Sprintf("method %q is a one-time passcode, which only works once", method)
+	}
+	return fmt.Sprintf("unknown method %q; use push, phone, sms or a passcode", method)
+}
+
+// hostProblem describes why a profile host can't be resolved, if it can't
+func hostProblem(ctx context.Context, host string) string {
+	name, _, _ := strings.Cut(host, "/")
+	if h, _, err := net.SplitHostPort(name); err == nil {
+		name = h
+	}
+	ctx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, name); err != nil {
+		return fmt.Sprintf("host %q does not resolve: %v", host, err)
+	}
+	return ""
+}
+
+// checkProfile checks one profile for what would make a connect with it
+// fail before the server is asked anything
+func checkProfile(ctx context.Context, name string, p profile, execProblem string) profileCheck {
+	var problems []string
+	if err := validateProfile(name, p); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if p.Username == "" {
+		problems = append(problems, "no username; connect would need --username")
+	}
+	for _, method := range append([]string{p.Method}, p.FallbackMethods...) {
+		if problem := methodProblem(method); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for _, host := range append([]string{p.Host}, p.FallbackHosts...) {
+		if !validHost(host) {
+			continue // already reported
+		}
+		if problem := hostProblem(ctx, host); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if execProblem != "" {
+		problems = append(problems, execProblem)
+	}
+	return profileCheck{Name: name, OK: len(problems) == 0, Problems: problems}
+}
+
+// profileValidateAction handles the profile validate command
+func profileValidateAction(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(cmd.String("config"))
+	if err != nil {
+		return err
+	}
+
+	names := cmd.Args().Slice()
+	switch {
+	case cmd.Bool("all") && len(names) > 0:
+		return fmt.Errorf("profile validate takes profile names or --all, not both")
+	case cmd.Bool("all"):
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("no profiles in %s", cmd.String("config"))
+		}
+	case len(names) == 0:
+		return fmt.Errorf("profile validate requires a profile name, or --all")
+	}
+	for _, name := range names {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("no profile named %q in %s", name, cmd.String("config"))
+		}
+	}
+
+	// Every profile connects with the same client
+	var execProblem string
+	if vpnExec, err := getVPNExec(cmd); err != nil {
+		execProblem = err.Error()
+	} else if _, err := exec.LookPath(vpnExec); err != nil {
+		execProblem = fmt.Sprintf("VPN executable %s is missing or not executable", vpnExec)
+	}
+
+	var checks profileChecks
+	failed := 0
+	for _, name := range names {
+		check := checkProfile(ctx, name, cfg.Profiles[name], execProblem)
+		if !check.OK {
+			failed++
+		}
+		checks = append(checks, check)
+	}
+	if err := render(checks, cmd.String("output")); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d profiles have problems", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Default prompt symbols, each with a trailing space to separate it from
+// the rest of the prompt
+const (
+	promptSymbolUnicode = "🔒 "
+	promptSymbolASCII   = "[vpn] "
+)
+
+// promptState works out whether the VPN is up as cheaply as possible: a
+// recent cached status, then the tunnel interface (holding the lease's
+// address, or named after Cisco), then "vpn status" bounded
+// by timeout. Anything it can't tell in time counts as disconnected.
+func promptState(vpnExec string, ttl, timeout time.Duration) bool {
+	if status, ok := cachedStatus(vpnExec, ttl, true); ok {
+		return status.Connected
+	}
+	var lastAddr string
+	if lease := readLease(); lease != nil {
+		lastAddr = lease.ClientAddress
+	}
+	if tunnelStillUp(lastAddr) {
+		slog.Debug("VPN tunnel interface is up; skipping vpn status")
+		saveStatusCache(vpnExec, newVPNStatus(true), true)
+		return true
+	}
+
+	// A hung client must not hold up the shell; the timeout kills it
+	ctx, cancel := context.WithTimeout(commandCtx, timeout)
+	defer cancel()
+	state := vpnStateCtx(ctx, vpnExec)
+	if state == stateUnknown {
+		return false
+	}
+	saveStatusCache(vpnExec, newVPNStatus(state == stateConnected), true)
+	return state == stateConnected
+}
+
+// promptAction handles the prompt command. It never fails, since an error
+// would end up in the user's prompt.
+func promptAction(ctx context.Context, cmd *cli.Command) error {
+	symbol := cmd.String("disconnected-symbol")
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		slog.Debug("no VPN client for the prompt", "error", err)
+	} else if promptState(vpnExec, cmd.Duration("cache-ttl"), cmd.Duration("timeout")) {
+		symbol = cmd.String("connected-symbol")
+		if !cmd.IsSet("connected-symbol") && (asciiSpinner || !terminalSupportsUnicode()) {
+			symbol = promptSymbolASCII
+		}
+	}
+	fmt.Print(symbol)
+	return nil
+}
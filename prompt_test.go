@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPromptStateLeavesCommandContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the client")
+	}
+	dir := t.TempDir()
+	t.Setenv("SECCLI_STATE_DIR", dir)
+	hung := filepath.Join(dir, "vpn")
+	if err := os.WriteFile(hung, []byte("#!/bin/sh\nsleep 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	if promptState(hung, 0, 100*time.Millisecond) {
+		t.Error("a hung client counted as connected")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("promptState took %s despite its 100ms timeout", elapsed)
+	}
+	if err := timedOut(); err != nil {
+		t.Errorf("after promptState, timedOut() = %v", err)
+	}
+	if _, err := runCommand("true"); err != nil {
+		t.Errorf("after promptState, runCommand failed: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseProxy validates a --proxy URL and applies --proxy-auth credentials
+// given as user:password
+func parseProxy(raw, auth string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --proxy: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid --proxy %q: scheme must be http, https, socks5 or socks5h", u.Redacted())
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid --proxy %q: missing host", u.Redacted())
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid --proxy %q: unexpected path", u.Redacted())
+	}
+
+	if auth != "" {
+		user, password, ok := strings.Cut(auth, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("invalid --proxy-auth: expected user:password")
+		}
+		u.User = url.UserPassword(user, password)
+	}
+	return u, nil
+}
+
+// proxyEnv returns the environment variables that point the VPN client at
+// the proxy. Both spellings are set since tools disagree on the case.
+func proxyEnv(proxy *url.URL) []string {
+	if proxy == nil {
+		return nil
+	}
+	value := proxy.String()
+	names := []string{"HTTPS_PROXY", "HTTP_PROXY"}
+	if strings.HasPrefix(proxy.Scheme, "socks") {
+		names = append(names, "ALL_PROXY")
+	}
+
+	var env []string
+	for _, name := range names {
+		env = append(env, name+"="+value, strings.ToLower(name)+"="+value)
+	}
+	return env
+}
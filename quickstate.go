@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// tunnelStillUp checks, without spawning the VPN client, whether the
+// network interfaces show a live Cisco session: one still holding lastAddr,
+// the client address of the last known session, or a Cisco-named tunnel.
+// A false result is not conclusive, since most platforms give the tunnel a
+// generic name.
+func tunnelStillUp(lastAddr string) bool {
+	if assumedState != nil {
+		return *assumedState == stateConnected
+	}
+	if lastAddr != "" && interfaceWithAddr(lastAddr) != "" {
+		return true
+	}
+	tunnels, err := findActiveTunnels()
+	if err != nil {
+		return false
+	}
+	for _, t := range tunnels {
+		if strings.HasPrefix(t.Tool, "Cisco") {
+			return true
+		}
+	}
+	return false
+}
+
+// pollState is vpnState for callers that poll: while the tunnel is visibly
+// up it skips "vpn status", which is by far the slower check
+func pollState(vpnExec, lastAddr string) connState {
+	if tunnelStillUp(lastAddr) {
+		slog.Debug("VPN tunnel interface is up; skipping vpn status")
+		return stateConnected
+	}
+	return vpnState(vpnExec)
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package main
+
+import "testing"
+
+// The benchmarks compare the fast state check, which only looks at the
+// network interfaces, with "vpn status", run against a connected stub
+// client. A session on the loopback address stands in for a visible tunnel.
+
+// connectedStub connects the stub client and points this process's
+// "vpn status" calls at the same session
+func connectedStub(b *testing.B) *stubEnv {
+	b.Helper()
+	e := newStubEnv(b)
+	if out, code := e.connect(b, "password"); code != 0 {
+		b.Fatalf("connect exited %d:\n%s", code, out)
+	}
+	e.setenv(b)
+	return e
+}
+
+func BenchmarkTunnelStillUp(b *testing.B) {
+	b.Run("address", func(b *testing.B) {
+		for b.Loop() {
+			if !tunnelStillUp("127.0.0.1") {
+				b.Fatal("loopback address not found")
+			}
+		}
+	})
+	b.Run("no address", func(b *testing.B) {
+		for b.Loop() {
+			tunnelStillUp("")
+		}
+	})
+}
+
+func BenchmarkPollState(b *testing.B) {
+	e := connectedStub(b)
+	b.Run("tunnel up", func(b *testing.B) {
+		for b.Loop() {
+			if pollState(e.vpn, "127.0.0.1") != stateConnected {
+				b.Fatal("not connected")
+			}
+		}
+	})
+	b.Run("falls back to vpn status", func(b *testing.B) {
+		for b.Loop() {
+			if pollState(e.vpn, "") != stateConnected {
+				b.Fatal("not connected")
+			}
+		}
+	})
+}
+
+func BenchmarkVPNState(b *testing.B) {
+	e := connectedStub(b)
+	for b.Loop() {
+		if vpnState(e.vpn) != stateConnected {
+			b.Fatal("not connected")
+		}
+	}
+}
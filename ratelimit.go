@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// mfaAttemptsFile records when connects that needed fresh MFA were attempted
+const mfaAttemptsFile = "mfa-attempts.json"
+
+// mfaWindow is the period over which --mfa-rate-limit counts attempts
+const mfaWindow = time.Hour
+
+// errMFAThrottled is returned by reserveMFAAttempt once the limit is reached
+var errMFAThrottled = errors.New("refusing to trigger another MFA prompt")
+
+// reserveMFAAttempt records an MFA attempt, or refuses it if limit attempts
+// were already made within the last hour. A limit of 0 disables throttling.
+func reserveMFAAttempt(limit int, now time.Time) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	path, err := statePath(mfaAttemptsFile)
+	if err != nil {
+		return err
+	}
+
+	var attempts []time.Time
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &attempts); err != nil {
+			slog.Debug("discarding unreadable MFA attempt history", "path", path, "error", err)
+			attempts = nil
+		}
+	}
+
+	// Keep only the attempts inside the window
+	recent := attempts[:0]
+	for _, t := range attempts {
+		if now.Sub(t) < mfaWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= limit {
+		retryAt := recent[0].Add(mfaWindow)
+		slog.Info("MFA attempt throttled", "limit", limit, "recent", len(recent), "retry_at", retryAt)
+		return fmt.Errorf("%w: %d attempts in the last hour (limit %d); try again after %s",
+			errMFAThrottled, len(recent), limit, retryAt.Local().Format(time.Kitchen))
+	}
+
+	recent = append(recent, now)
+	data, err = json.Marshal(recent)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	slog.Debug("recorded MFA attempt", "recent", len(recent), "limit", limit)
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// connectResult describes the session a successful connect established
+type connectResult struct {
+	vpnStatus
+	Host          string `json:"host"`
+	Attempt       int    `json:"attempt"`
+	Server        string `json:"server,omitempty"`
+	ClientAddress string `json:"client_address,omitempty"`
+	Interface     string `json:"interface,omitempty"`
+	Duration      string `json:"duration"`
+	Method        string `json:"method"`
+
+	// Egress is filled in by --check-egress
+	Egress *egressReport `json:"egress,omitempty"`
+	// Routes is filled in by --route-changes
+	Routes *routeChanges `json:"routes,omitempty"`
+}
+
+// newConnectResult fills a result from "vpn stats" once the tunnel is up.
+// Stats are best effort: a client that can't report them still connected.
+// method is the MFA method used, as for reportedMethod.
+func newConnectResult(vpnExec, method string, elapsed time.Duration) connectResult {
+	result := connectResult{
+		vpnStatus: newVPNStatus(true),
+		Duration:  elapsed.Round(100 * time.Millisecond).String(),
+		Method:    method,
+	}
+
+	stats, err := getStats(vpnExec)
+	if err != nil {
+		slog.Debug("could not read session details", "error", err)
+		return result
+	}
+	result.Server = stats.Server
+	result.ClientAddress = stats.ClientAddress
+	result.Interface = interfaceWithAddr(stats.ClientAddress)
+	result.Transport = transportOf(stats.Protocol)
+	return result
+}
+
+// reportedMethod names the MFA method without echoing a passcode
+func reportedMethod(method string) string {
+	switch {
+	case method == "":
+		return "none"
+	case isPasscode(method):
+		return "passcode"
+	}
+	return method
+}
+
+// interfaceWithAddr returns the name of the interface holding ip, or "" if
+// none does
+func interfaceWithAddr(ip string) string {
+	want := net.ParseIP(ip)
+	if want == nil {
+		return ""
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(want) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
+// String formats the result for text output
+func (r connectResult) String() string {
+	orUnknown := func(v string) string {
+		if v == "" {
+			return "(not reported)"
+		}
+		return v
+	}
+
+	var b strings.Builder
+	b.WriteString("VPN connection successful\n")
+	fmt.Fprintf(&b, "  Server:    %s\n", orUnknown(r.Server))
+	fmt.Fprintf(&b, "  Address:   %s\n", orUnknown(r.ClientAddress))
+	fmt.Fprintf(&b, "  Interface: %s\n", orUnknown(r.Interface))
+	fmt.Fprintf(&b, "  Method:    %s\n", r.Method)
+	if r.Transport != "" {
+		fmt.Fprintf(&b, "  Transport: %s\n", strings.ToUpper(r.Transport))
+	}
+	fmt.Fprintf(&b, "  Took:      %s", r.Duration)
+	if r.Attempt > 1 {
+		fmt.Fprintf(&b, "\n  Attempt:   %d (%s with %s)", r.Attempt, r.Host, r.Method)
+	}
+	if r.Egress != nil {
+		b.WriteString("\n" + indent(r.Egress.String(), "  "))
+	}
+	if r.Routes != nil {
+		b.WriteString("\n" + indent(r.Routes.String(), "  "))
+	}
+	return b.String()
+}
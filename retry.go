@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// retryPolicy bounds how often and how long connect keeps trying. The zero
+// value makes one attempt per host/method combination, back to back.
+type retryPolicy struct {
+	// MaxAttempts caps the attempts; beyond the number of combinations
+	// they start over from the first. 0 means one per combination.
+	MaxAttempts int
+	// MaxTime stops new attempts once this long has passed since the
+	// first; 0 means no limit. A running attempt is not interrupted.
+	MaxTime time.Duration
+	// BackoffBase is the wait before the second attempt, doubling for each
+	// one after, up to BackoffCap (if set)
+	BackoffBase time.Duration
+	BackoffCap  time.Duration
+}
+
+// attempts returns how many attempts to make over steps combinations
+func (p retryPolicy) attempts(steps int) int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return steps
+}
+
+// backoff returns the wait after failed attempts (counting from 1)
+func (p retryPolicy) backoff(failed int) time.Duration {
+	if p.BackoffBase <= 0 || failed < 1 {
+		return 0
+	}
+	d := p.BackoffBase
+	for i := 1; i < failed; i++ {
+		// Stop doubling at the cap, or before the duration overflows
+		if (p.BackoffCap > 0 && d >= p.BackoffCap) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if p.BackoffCap > 0 && d > p.BackoffCap {
+		d = p.BackoffCap
+	}
+	return d
+}
+
+// sleep waits d, returning early with the context's error if it is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy retryPolicy
+		failed int
+		want   time.Duration
+	}{
+		{"no base", retryPolicy{}, 3, 0},
+		{"no failures yet", retryPolicy{BackoffBase: time.Second}, 0, 0},
+		{"negative failures", retryPolicy{BackoffBase: time.Second}, -1, 0},
+		{"first failure waits the base", retryPolicy{BackoffBase: time.Second}, 1, time.Second},
+		{"doubles after each failure", retryPolicy{BackoffBase: time.Second}, 4, 8 * time.Second},
+		{"capped", retryPolicy{BackoffBase: time.Second, BackoffCap: 5 * time.Second}, 4, 5 * time.Second},
+		{"cap below the base", retryPolicy{BackoffBase: time.Minute, BackoffCap: time.Second}, 1, time.Second},
+		{"large count stays capped", retryPolicy{BackoffBase: time.Second, BackoffCap: time.Minute}, 1000, time.Minute},
+		{"large count without a cap doesn't overflow", retryPolicy{BackoffBase: time.Second}, 1000, time.Second << 33},
+		{"nanosecond base doesn't overflow", retryPolicy{BackoffBase: time.Nanosecond}, math.MaxInt32, time.Duration(1 << 62)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.backoff(tt.failed)
+			if got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.failed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyAttempts(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy retryPolicy
+		steps  int
+		want   int
+	}{
+		{"one per combination by default", retryPolicy{}, 3, 3},
+		{"max attempts wins", retryPolicy{MaxAttempts: 5}, 2, 5},
+		{"max attempts below the combinations", retryPolicy{MaxAttempts: 1}, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.attempts(tt.steps); got != tt.want {
+				t.Errorf("attempts(%d) = %d, want %d", tt.steps, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// routeChanges are the routes a connect or disconnect added and removed
+type routeChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// String formats the changes for text output
+func (c routeChanges) String() string {
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		return "Routes: unchanged"
+	}
+	var lines []string
+	if len(c.Added) > 0 {
+		lines = append(lines, "Routes added:")
+		for _, route := range c.Added {
+			lines = append(lines, "  + "+route)
+		}
+	}
+	if len(c.Removed) > 0 {
+		lines = append(lines, "Routes removed:")
+		for _, route := range c.Removed {
+			lines = append(lines, "  - "+route)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffRoutes compares two routing table snapshots
+func diffRoutes(before, after []string) routeChanges {
+	changes := routeChanges{Added: []string{}, Removed: []string{}}
+	inBefore := map[string]bool{}
+	for _, route := range before {
+		inBefore[route] = true
+	}
+	inAfter := map[string]bool{}
+	for _, route := range after {
+		inAfter[route] = true
+		if !inBefore[route] {
+			changes.Added = append(changes.Added, route)
+		}
+	}
+	for _, route := range before {
+		if !inAfter[route] {
+			changes.Removed = append(changes.Removed, route)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// routesOn keeps the routes through an interface, given by its name or, as
+// Windows lists it, its address
+func routesOn(routes []string, iface, addr string) []string {
+	var kept []string
+	for _, route := range routes {
+		for _, field := range strings.Fields(route) {
+			if field == iface || (addr != "" && field == addr) {
+				kept = append(kept, route)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// readRoutes snapshots the routing table as one line per route. Linux is
+// read from /proc; elsewhere "netstat -rn" is parsed loosely, keeping only
+// the columns that don't change while a route is in use.
+func readRoutes() ([]string, error) {
+	if runtime.GOOS == "linux" {
+		routes, err := readProcRoutes("/proc/net/route", parseIPv4Route)
+		if err != nil {
+			return nil, err
+		}
+		// IPv6 may be disabled, which removes the file
+		if v6, err := readProcRoutes("/proc/net/ipv6_route", parseIPv6Route); err == nil {
+			routes = append(routes, v6...)
+		}
+		return routes, nil
+	}
+
+	output, err := runCommand("netstat", "-rn")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %v", err)
+	}
+	var routes []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !isRouteLine(fields[0]) {
+			continue
+		}
+		// macOS appends use counts and expiry timers
+		if runtime.GOOS == "darwin" && len(fields) > 4 {
+			fields = fields[:4]
+		}
+		routes = append(routes, strings.Join(fields, " "))
+	}
+	return routes, nil
+}
+
+// isRouteLine tells netstat route lines from headers: routes start with an
+// address, "default", or a link-layer destination
+func isRouteLine(first string) bool {
+	if first == "default" || strings.HasPrefix(first, "link#") {
+		return true
+	}
+	host, _, _ := strings.Cut(first, "/")
+	host, _, _ = strings.Cut(host, "%")
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	// macOS abbreviates networks, e.g. "10" or "169.254"
+	_, err := strconv.Atoi(strings.ReplaceAll(host, ".", ""))
+	return err == nil
+}
+
+// readProcRoutes reads a /proc routing table, skipping its header
+func readProcRoutes(path string, parse func([]string) (string, bool)) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %v", err)
+	}
+	defer f.Close()
+
+	var routes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if route, ok := parse(strings.Fields(scanner.Text())); ok {
+			routes = append(routes, route)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %v", err)
+	}
+	return routes, nil
+}
+
+// parseIPv4Route formats a /proc/net/route line, whose addresses are
+// little-endian hex
+func parseIPv4Route(fields []string) (string, bool) {
+	if len(fields) < 8 {
+		return "", false
+	}
+	dest, ok1 := procIPv4(fields[1])
+	gateway, ok2 := procIPv4(fields[2])
+	mask, ok3 := procIPv4(fields[7])
+	if !ok1 || !ok2 || !ok3 {
+		return "", false // the header
+	}
+	ones, _ := net.IPMask(mask.To4()).Size()
+	return formatRoute(fmt.Sprintf("%s/%d", dest, ones), gateway, fields[0], fields[6]), true
+}
+
+// procIPv4 decodes a little-endian hex IPv4 address
+func procIPv4(s string) (net.IP, bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil, false
+	}
+	return net.IPv4(b[3], b[2], b[1], b[0]), true
+}
+
+// parseIPv6Route formats a /proc/net/ipv6_route line
+func parseIPv6Route(fields []string) (string, bool) {
+	if len(fields) < 10 {
+		return "", false
+	}
+	dest, err1 := hex.DecodeString(fields[0])
+	prefix, err2 := strconv.ParseUint(fields[1], 16, 8)
+	gateway, err3 := hex.DecodeString(fields[4])
+	metric, err4 := strconv.ParseUint(fields[5], 16, 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || len(dest) != 16 || len(gateway) != 16 {
+		return "", false
+	}
+	return formatRoute(fmt.Sprintf("%s/%d", net.IP(dest), prefix), net.IP(gateway), fields[9], strconv.FormatUint(metric, 10)), true
+}
+
+// formatRoute renders a route like "ip route" does
+func formatRoute(dest string, gateway net.IP, iface, metric string) string {
+	route := dest
+	if !gateway.IsUnspecified() {
+		route += " via " + gateway.String()
+	}
+	return route + " dev " + iface + " metric " + metric
+}
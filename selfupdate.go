@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// latestReleaseURL is the GitHub API endpoint for the newest release;
+// SECCLI_UPDATE_URL replaces it, e.g. for a mirror
+const latestReleaseURL = "https://api.github.com/repos/da-luce/cornell_vpn_cli/releases/latest"
+
+// updateTimeout bounds the whole check and download
+const updateTimeout = 5 * time.Minute
+
+// checksumsAsset lists the SHA-256 of every release asset, in sha256sum format
+const checksumsAsset = "checksums.txt"
+
+// release is the part of a GitHub release seccli reads
+type release struct {
+	Tag    string         `json:"tag_name"`
+	Assets []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is one downloadable file of a release
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset finds a release file by name
+func (r release) asset(name string) (releaseAsset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// binaryAssetName is the release file for this OS and architecture
+func binaryAssetName() string {
+	name := fmt.Sprintf("seccli_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// parseVersion splits a release version like "v1.2.3" into numbers. Builds
+// that aren't a plain release, like "dev" or a pseudo-version, don't parse.
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	fields := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// newerVersion reports whether release version a is newer than b
+func newerVersion(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
+
+// fetch GETs a URL, failing on any status but 200
+func fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "seccli/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// latestRelease asks GitHub (or SECCLI_UPDATE_URL) for the newest release
+func latestRelease(ctx context.Context) (release, error) {
+	url := latestReleaseURL
+	if override := os.Getenv("SECCLI_UPDATE_URL"); override != "" {
+		url = override
+	}
+	body, err := fetch(ctx, url)
+	if err != nil {
+		return release{}, fmt.Errorf("failed to check for updates: %v", err)
+	}
+	defer body.Close()
+	var r release
+	if err := json.NewDecoder(body).Decode(&r); err != nil {
+		return release{}, fmt.Errorf("failed to parse release information: %v", err)
+	}
+	return r, nil
+}
+
+// releaseChecksum looks up an asset's SHA-256 in the release's checksums
+func releaseChecksum(ctx context.Context, r release, name string) (string, error) {
+	asset, ok := r.asset(checksumsAsset)
+	if !ok {
+		return "", fmt.Errorf("release %s has no %s to verify the download against", r.Tag, checksumsAsset)
+	}
+	body, err := fetch(ctx, asset.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksums: %v", err)
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks binary mode with a "*" before the name
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to download checksums: %v", err)
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// replaceExecutable downloads asset next to the running binary, verifies
+// it against checksum, and renames it over the binary
+func replaceExecutable(ctx context.Context, asset releaseAsset, checksum string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the running binary: %v", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to locate the running binary: %v", err)
+	}
+
+	// A temporary file in the same directory can be renamed atomically
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".seccli-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write update next to %s: %v", exe, err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	body, err := fetch(ctx, asset.URL)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to download %s: %v", asset.Name, err)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), body)
+	body.Close()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", asset.Name, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != checksum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s; not installing it", asset.Name, checksum, sum)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to install update: %v", err)
+	}
+
+	// Windows can't replace a running executable, but it can rename one
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to install update: %v", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to install update: %v", err)
+	}
+	return nil
+}
+
+// selfUpdateAction handles the self-update command
+func selfUpdateAction(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	current := currentVersion().Version
+	r, err := latestRelease(ctx)
+	if err != nil {
+		return err
+	}
+	latest, ok := parseVersion(r.Tag)
+	if !ok {
+		return fmt.Errorf("latest release has an unrecognized version %q", r.Tag)
+	}
+	slog.Info("found latest release", "version", r.Tag, "current", current)
+
+	installed, ok := parseVersion(current)
+	switch {
+	case !ok && !cmd.Bool("force"):
+		if cmd.Bool("check-only") {
+			fmt.Printf("seccli %s is the latest release; this is a development build (%s)\n", r.Tag, current)
+			return nil
+		}
+		return fmt.Errorf("this is a development build (%s); pass --force to replace it with release %s", current, r.Tag)
+	case ok && !newerVersion(latest, installed) && !cmd.Bool("force"):
+		fmt.Printf("seccli %s is up to date\n", current)
+		return nil
+	}
+	if cmd.Bool("check-only") {
+		fmt.Printf("seccli %s is available (installed: %s)\n", r.Tag, current)
+		return nil
+	}
+
+	name := binaryAssetName()
+	asset, ok := r.asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s/%s (%s)", r.Tag, runtime.GOOS, runtime.GOARCH, name)
+	}
+	checksum, err := releaseChecksum(ctx, r, name)
+	if err != nil {
+		return err
+	}
+
+	s := newSpinner(fmt.Sprintf(" Downloading seccli %s...", r.Tag))
+	s.Start()
+	err = replaceExecutable(ctx, asset, checksum)
+	s.Stop()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Updated seccli from %s to %s\n", current, r.Tag)
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"golang.org/x/term"
+)
+
+// asciiSpinner forces the ASCII spinner charset when set by --ascii
+var asciiSpinner bool
+
+// noSpinner turns the spinner off entirely when set by --no-spinner
+var noSpinner bool
+
+// spinners are the spinners created so far, for stopSpinners. Inactive ones
+// are dropped once the list grows past maxTrackedSpinners.
+var (
+	spinnersMu sync.Mutex
+	spinners   []*spinner.Spinner
+)
+
+// maxTrackedSpinners bounds spinners for long runs such as the dashboard
+const maxTrackedSpinners = 32
+
+// Spinner charsets from github.com/briandowns/spinner
+var (
+	unicodeCharset = spinner.CharSets[14] // ⠋⠙⠹⠸...
+	asciiCharset   = spinner.CharSets[9]  // |/-\
+)
+
+// newSpinner creates a spinner with the given suffix, using an ASCII
+// charset when the terminal is unlikely to render Unicode
+func newSpinner(suffix string) *spinner.Spinner {
+	charset := unicodeCharset
+	if asciiSpinner || !terminalSupportsUnicode() {
+		charset = asciiCharset
+	}
+	s := spinner.New(charset, 100*time.Millisecond)
+	s.Suffix = suffix
+	if noSpinner || verbosity >= verbosityMilestones {
+		// Log lines on stderr would tear through the animation
+		s.Disable()
+	}
+
+	spinnersMu.Lock()
+	if len(spinners) >= maxTrackedSpinners {
+		active := spinners[:0]
+		for _, tracked := range spinners {
+			if tracked.Active() {
+				active = append(active, tracked)
+			}
+		}
+		spinners = active
+	}
+	spinners = append(spinners, s)
+	spinnersMu.Unlock()
+	return s
+}
+
+// stopSpinners stops any running spinner and shows the cursor again, so a
+// panic doesn't leave the terminal with a hidden cursor and a stray frame
+func stopSpinners() {
+	spinnersMu.Lock()
+	defer spinnersMu.Unlock()
+	for _, s := range spinners {
+		s.Stop()
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprint(os.Stdout, "\033[?25h")
+	}
+}
+
+// terminalSupportsUnicode guesses whether the terminal can render
+// Unicode from the OS and the TERM/locale environment variables
+func terminalSupportsUnicode() bool {
+	if runtime.GOOS == "windows" {
+		// Legacy conhost mangles braille; Windows Terminal sets WT_SESSION
+		return os.Getenv("WT_SESSION") != ""
+	}
+
+	switch os.Getenv("TERM") {
+	case "dumb", "linux":
+		return false
+	}
+
+	// The first non-empty locale variable wins, as with setlocale(3)
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
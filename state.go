@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stateDir returns the directory for seccli's runtime state, creating it if
+// needed. SECCLI_STATE_DIR overrides the per-user cache location.
+func stateDir() (string, error) {
+	dir := os.Getenv("SECCLI_STATE_DIR")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate state directory: %v", err)
+		}
+		dir = filepath.Join(cache, "seccli")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %v", err)
+	}
+	return dir, nil
+}
+
+// statePath returns the path of a file in the state directory
+func statePath(name string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
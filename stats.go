@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// vpnStats holds the parsed output of "vpn stats"
+type vpnStats struct {
+	SchemaVersion int               `json:"schema_version"`
+	State         string            `json:"state,omitempty"`
+	Duration      string            `json:"duration,omitempty"`
+	Server        string            `json:"server,omitempty"`
+	ClientAddress string            `json:"client_address,omitempty"`
+	TunnelMode    string            `json:"tunnel_mode,omitempty"`
+	Protocol      string            `json:"protocol,omitempty"`
+	Cipher        string            `json:"cipher,omitempty"`
+	BytesSent     int64             `json:"bytes_sent"`
+	BytesReceived int64             `json:"bytes_received"`
+	Fields        map[string]string `json:"fields"`
+
+	showAll bool
+}
+
+// statsKeys maps the labels different client versions print to the
+// vpnStats field they fill; the first label present wins
+var statsKeys = map[string][]string{
+	"state":          {"tunnel state", "connection state"},
+	"duration":       {"duration", "time connected"},
+	"server":         {"server address", "server"},
+	"client_address": {"client address (ipv4)", "client (ipv4)", "client address"},
+	"tunnel_mode":    {"tunnel mode (ipv4)", "tunnel mode"},
+	"protocol":       {"protocol", "transport protocol"},
+	"cipher":         {"protocol cipher", "cipher"},
+	"bytes_sent":     {"bytes sent"},
+	"bytes_received": {"bytes received"},
+}
+
+// parseStats extracts "Key: Value" lines from "vpn stats" output
+func parseStats(output string) vpnStats {
+	stats := vpnStats{SchemaVersion: statusSchemaVersion, Fields: map[string]string{}}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		// Skip section headers, prompts and notices like ">> state: ..."
+		if key == "" || value == "" || strings.HasPrefix(key, ">>") || strings.HasPrefix(key, "[") {
+			continue
+		}
+		if _, seen := stats.Fields[key]; !seen {
+			stats.Fields[key] = value
+		}
+	}
+
+	lookup := func(field string) string {
+		for _, label := range statsKeys[field] {
+			for key, value := range stats.Fields {
+				if strings.EqualFold(key, label) {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+
+	stats.State = lookup("state")
+	stats.Duration = lookup("duration")
+	stats.Server = lookup("server")
+	stats.ClientAddress = lookup("client_address")
+	stats.TunnelMode = lookup("tunnel_mode")
+	stats.Protocol = lookup("protocol")
+	stats.Cipher = lookup("cipher")
+	stats.BytesSent = parseCount(lookup("bytes_sent"))
+	stats.BytesReceived = parseCount(lookup("bytes_received"))
+	return stats
+}
+
+// parseCount parses a byte counter, tolerating thousands separators
+func parseCount(value string) int64 {
+	n, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// String formats the stats for text output
+func (s vpnStats) String() string {
+	orUnknown := func(v string) string {
+		if v == "" {
+			return "(not reported)"
+		}
+		return v
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "State:          %s\n", orUnknown(s.State))
+	fmt.Fprintf(&b, "Duration:       %s\n", orUnknown(s.Duration))
+	fmt.Fprintf(&b, "Server:         %s\n", orUnknown(s.Server))
+	fmt.Fprintf(&b, "Client Address: %s\n", orUnknown(s.ClientAddress))
+	fmt.Fprintf(&b, "Tunnel Mode:    %s\n", orUnknown(s.TunnelMode))
+	fmt.Fprintf(&b, "Protocol:       %s\n", orUnknown(s.Protocol))
+	fmt.Fprintf(&b, "Cipher:         %s\n", orUnknown(s.Cipher))
+	fmt.Fprintf(&b, "Bytes Sent:     %d\n", s.BytesSent)
+	fmt.Fprintf(&b, "Bytes Received: %d", s.BytesReceived)
+
+	if s.showAll {
+		keys := make([]string, 0, len(s.Fields))
+		for k := range s.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\n\nAll fields:")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n  %s: %s", k, s.Fields[k])
+		}
+	}
+	return b.String()
+}
+
+// getStats runs "vpn stats" and parses its output
+func getStats(vpnExec string) (vpnStats, error) {
+	output, err := runCommand(vpnExec, "stats")
+	if err != nil {
+		return vpnStats{}, fmt.Errorf("VPN stats command failed: %v", err)
+	}
+	return parseStats(output), nil
+}
+
+// statsAction handles the stats command
+func statsAction(ctx context.Context, cmd *cli.Command) error {
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	s := newSpinner(" Checking VPN Status...")
+	s.Start()
+	defer s.Stop()
+
+	if !vpnConnected(vpnExec) {
+		return fmt.Errorf("VPN is not connected.")
+	}
+
+	stats, err := getStats(vpnExec)
+	if err != nil {
+		return err
+	}
+	stats.showAll = cmd.Bool("all")
+
+	s.Stop()
+	if path := cmd.String("output-file"); path != "" {
+		return renderFile(path, stats, cmd.String("output"))
+	}
+	return render(stats, cmd.String("output"))
+}
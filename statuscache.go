@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// statusCacheFile holds the last status --cache-ttl may reuse
+const statusCacheFile = "status-cache.json"
+
+// statusCache is a status result and when it was taken. VPNExec keeps
+// results from different clients apart; StateOnly marks a result from
+// status --quiet, which has no fields.
+type statusCache struct {
+	VPNExec   string    `json:"vpn_exec"`
+	Time      time.Time `json:"time"`
+	StateOnly bool      `json:"state_only,omitempty"`
+	Status    vpnStatus `json:"status"`
+}
+
+// cachedStatus returns the cached status for vpnExec if it is younger than
+// ttl and from this schema version. Unless stateOnly, a result without
+// fields doesn't count.
+func cachedStatus(vpnExec string, ttl time.Duration, stateOnly bool) (vpnStatus, bool) {
+	if ttl <= 0 || assumedState != nil {
+		return vpnStatus{}, false
+	}
+	path, err := statePath(statusCacheFile)
+	if err != nil {
+		return vpnStatus{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return vpnStatus{}, false
+	}
+	var cached statusCache
+	if err := json.Unmarshal(data, &cached); err != nil {
+		slog.Debug("ignoring unreadable status cache", "path", path, "error", err)
+		return vpnStatus{}, false
+	}
+	age := time.Since(cached.Time)
+	if cached.VPNExec != vpnExec || cached.Status.SchemaVersion != statusSchemaVersion || age < 0 || age >= ttl {
+		return vpnStatus{}, false
+	}
+	if cached.StateOnly && !stateOnly {
+		return vpnStatus{}, false
+	}
+	slog.Debug("using cached status", "age", age.String())
+	return cached.Status, true
+}
+
+// saveStatusCache records a status for later runs with --cache-ttl. It is
+// replaced atomically, since concurrent prompts may be reading it; failures
+// are only logged.
+func saveStatusCache(vpnExec string, status vpnStatus, stateOnly bool) {
+	if assumedState != nil {
+		return
+	}
+	path, err := statePath(statusCacheFile)
+	if err != nil {
+		return
+	}
+	status.Lease, status.LastError = nil, nil // read fresh every time
+	data, _ := json.Marshal(statusCache{VPNExec: vpnExec, Time: time.Now(), StateOnly: stateOnly, Status: status})
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+statusCacheFile+".*")
+	if err != nil {
+		slog.Debug("failed to write status cache", "error", err)
+		return
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		slog.Debug("failed to write status cache", "error", err)
+	}
+}
+
+// clearStatusCache drops the cached status once a disconnect or failed
+// connect has changed it
+func clearStatusCache() {
+	path, err := statePath(statusCacheFile)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("failed to clear status cache", "error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// builtinStatusRules extract fields from "vpn status" output. Each rule is a
+// regular expression whose first capture group (or whole match) becomes the
+// field value.
+var builtinStatusRules = map[string]string{
+	"state":  `(?m)>>\s*state:\s*(.+?)\s*$`,
+	"notice": `(?m)>>\s*notice:\s*(.+?)\s*$`,
+}
+
+// compileStatusRules combines the built-in rules with user-supplied ones from
+// the config, which override built-ins of the same name
+func compileStatusRules(custom map[string]string) (map[string]*regexp.Regexp, error) {
+	sources := map[string]string{}
+	for name, pattern := range builtinStatusRules {
+		sources[name] = pattern
+	}
+	for name, pattern := range custom {
+		sources[name] = pattern
+	}
+
+	rules := make(map[string]*regexp.Regexp, len(sources))
+	for name, pattern := range sources {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status rule %q: %v", name, err)
+		}
+		rules[name] = re
+	}
+	return rules, nil
+}
+
+// parseStatus applies the rules to "vpn status" output. The last match of
+// each rule wins, since the client prints state transitions in order.
+func parseStatus(output string, rules map[string]*regexp.Regexp) map[string]string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := map[string]string{}
+	for _, name := range names {
+		matches := rules[name].FindAllStringSubmatch(output, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		last := matches[len(matches)-1]
+		if len(last) > 1 {
+			fields[name] = last[1]
+		} else {
+			fields[name] = last[0]
+		}
+	}
+	return fields
+}
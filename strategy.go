@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// connectStep is one host+method combination of a connect strategy
+type connectStep struct {
+	Host   string
+	Method string
+}
+
+// strategyMatrix orders the combinations to try: every host with the
+// preferred method first, then every host again with each fallback method
+func strategyMatrix(hosts, methods []string) []connectStep {
+	var steps []connectStep
+	for _, method := range methods {
+		for _, host := range hosts {
+			steps = append(steps, connectStep{Host: host, Method: method})
+		}
+	}
+	return steps
+}
+
+// resolveFallbacks returns the extra hosts and methods to try, from the
+// flags if given, otherwise from the profile
+func resolveFallbacks(cmd *cli.Command) ([]string, []string, error) {
+	hosts := cmd.StringSlice("fallback-host")
+	methods := cmd.StringSlice("fallback-method")
+	if name := cmd.String("profile"); name != "" {
+		p, err := lookupProfile(cmd.String("config"), name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !cmd.IsSet("fallback-host") {
+			hosts = p.FallbackHosts
+		}
+		if !cmd.IsSet("fallback-method") {
+			methods = p.FallbackMethods
+		}
+	}
+	// A Cisco profile's backup servers stand in for fallback hosts
+	if path := cmd.String("profile-file"); path != "" && len(hosts) == 0 && !cmd.IsSet("fallback-host") {
+		entry, err := ciscoHostEntryFor(path, cmd.String("profile-host"))
+		if err != nil {
+			return nil, nil, err
+		}
+		hosts = entry.backupHosts()
+	}
+	normalized := make([]string, len(methods))
+	for i, method := range methods {
+		normalized[i] = normalizeMethod(method)
+	}
+	return hosts, normalized, nil
+}
+
+// retryable tells whether a failed attempt is worth repeating with another
+// host or method. Recognized failures like an expired password, throttling,
+// a client that can't be started and cancellation would only fail again.
+func retryable(ctx context.Context, err error) bool {
+	var known knownFailure
+	var launch *launchError
+	switch {
+	case ctx.Err() != nil:
+		return false
+	case errors.Is(err, errAlreadyConnected), errors.Is(err, errOnsite), errors.Is(err, errMFAThrottled):
+		return false
+	case errors.As(err, &known), errors.As(err, &launch):
+		return false
+	}
+	return true
+}
+
+// connectWithStrategy tries each step in turn until one connects, within
+// the limits of opts.Retry. The password is asked for once and reused for
+// every attempt, including the retry after an agent restart.
+func connectWithStrategy(ctx context.Context, vpnExec string, opts connectOptions, steps []connectStep) (connectResult, error) {
+	attempts := opts.Retry.attempts(len(steps))
+	if attempts == 1 && !opts.RestartAgent && !opts.Takeover {
+		opts.Host, opts.Method = steps[0].Host, steps[0].Method
+		result, err := connectVPN(ctx, vpnExec, opts)
+		result.Host, result.Attempt = opts.Host, 1
+		return result, err
+	}
+
+	// With --takeover the first attempt deals with an existing session
+	if !opts.Takeover && vpnConnected(vpnExec) {
+		return connectResult{}, errAlreadyConnected
+	}
+	// With --interactive-prompts the client asks for them itself
+	if opts.Password == "" && !opts.InteractivePrompts {
+		password, err := getPassword("Enter VPN password: ")
+		if err != nil {
+			return connectResult{}, fmt.Errorf("failed to read password: %v", err)
+		}
+		opts.Password = password
+	}
+	if opts.AskSecondPassword && opts.SecondPassword == "" && !opts.InteractivePrompts {
+		password, err := getPassword("Enter second password: ")
+		if err != nil {
+			return connectResult{}, fmt.Errorf("failed to read second password: %v", err)
+		}
+		opts.SecondPassword = password
+	}
+
+	started := time.Now()
+	var failures []string
+	for i := 0; i < attempts; i++ {
+		step := steps[i%len(steps)]
+		opts.Host, opts.Method = step.Host, step.Method
+		opts.Attempt, opts.Attempts = i+1, attempts
+		slog.Info("connect attempt", "attempt", i+1, "of", attempts, "host", step.Host, "method", reportedMethod(step.Method))
+
+		result, err := connectRecovering(ctx, vpnExec, opts)
+		if err == nil {
+			result.Host, result.Attempt = step.Host, i+1
+			return result, nil
+		}
+		if !retryable(ctx, err) || attempts == 1 {
+			return connectResult{}, err
+		}
+
+		failure := fmt.Sprintf("%s with %s: %v", step.Host, reportedMethod(step.Method), err)
+		failures = append(failures, failure)
+		if i == attempts-1 {
+			break
+		}
+
+		wait := opts.Retry.backoff(i + 1)
+		if limit := opts.Retry.MaxTime; limit > 0 && time.Since(started)+wait >= limit {
+			return connectResult{}, fmt.Errorf("gave up after %d attempts, with the --max-retry-time of %s spent:\n%s",
+				i+1, limit, indent(strings.Join(failures, "\n"), "  "))
+		}
+		next := "trying the next combination"
+		if i+1 >= len(steps) {
+			next = "trying again"
+		}
+		if wait > 0 {
+			next += fmt.Sprintf(" in %s", wait)
+		}
+		fmt.Fprintf(os.Stderr, "Attempt %d of %d failed (%s); %s\n", i+1, attempts, failure, next)
+		if err := sleep(ctx, wait); err != nil {
+			return connectResult{}, err
+		}
+	}
+	if attempts == len(steps) {
+		return connectResult{}, fmt.Errorf("all %d host/method combinations failed:\n%s",
+			len(steps), indent(strings.Join(failures, "\n"), "  "))
+	}
+	return connectResult{}, fmt.Errorf("all %d attempts failed:\n%s", attempts, indent(strings.Join(failures, "\n"), "  "))
+}
+
+// connectRecovering is connectVPN with the recoveries the options allow:
+// restarting an unresponsive agent, or ending the session of another user
+// of this machine. Either is tried once.
+func connectRecovering(ctx context.Context, vpnExec string, opts connectOptions) (connectResult, error) {
+	result, err := connectVPN(ctx, vpnExec, opts)
+	switch {
+	case opts.RestartAgent && errors.Is(err, errAgentRestart):
+		fmt.Fprintln(os.Stderr, "The Cisco VPN agent is not responding; restarting it and trying again")
+		if err := restartAgent(ctx, vpnExec); err != nil {
+			if ctx.Err() != nil {
+				return connectResult{}, err
+			}
+			// Other hosts would hit the same agent
+			return connectResult{}, knownFailure(fmt.Sprintf("the Cisco VPN agent is not responding, and restarting it failed: %v", err))
+		}
+	case opts.Takeover && (errors.Is(err, errAlreadyConnected) || (errors.Is(err, errAnotherUser) && vpnConnected(vpnExec))):
+		fmt.Fprintln(os.Stderr, "Taking over: disconnecting the active VPN session first")
+		if err := disconnectVPN(vpnExec, opts.Verbose, false, nil); err != nil {
+			return connectResult{}, knownFailure(fmt.Sprintf("could not take over the active VPN session: %v", err))
+		}
+	default:
+		return result, err
+	}
+	return connectVPN(ctx, vpnExec, opts)
+}
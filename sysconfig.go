@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// systemConfigEnv overrides where the system-wide config is read from
+const systemConfigEnv = "SECCLI_SYSTEM_CONFIG"
+
+// lockedKey lists, in the system config only, the fields a user's config
+// can't override
+const lockedKey = "locked"
+
+// warnedLocked are the locked fields already warned about this run, since
+// the config is loaded several times
+var warnedLocked = map[string]bool{}
+
+// systemConfigPath returns the system-wide config file location, which
+// managed machines use for organization defaults
+func systemConfigPath() string {
+	if path := os.Getenv(systemConfigEnv); path != "" {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return filepath.Join(dir, "seccli", "config.json")
+	}
+	return "/etc/seccli/config.json"
+}
+
+// readConfigFields reads a config file as its top-level fields, returning
+// nil if it doesn't exist
+func readConfigFields(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return fields, nil
+}
+
+// systemLocks returns the fields the system config locks
+func systemLocks(path string, system map[string]json.RawMessage) ([]string, error) {
+	var locked []string
+	if raw, ok := system[lockedKey]; ok {
+		if err := json.Unmarshal(raw, &locked); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %q must be a list of field names: %v", path, lockedKey, err)
+		}
+	}
+	return locked, nil
+}
+
+// mergeConfigFields lays the user's fields over the system's. Objects
+// such as profiles are merged entry by entry, so a user can add profiles
+// to the organization's; other values are replaced. Locked fields keep the
+// system's value whole, with a warning if the user's config sets them.
+func mergeConfigFields(system, user map[string]json.RawMessage, locked []string, userPath string) map[string]json.RawMessage {
+	merged := map[string]json.RawMessage{}
+	for name, raw := range system {
+		if name != lockedKey {
+			merged[name] = raw
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(user)) {
+		raw := user[name]
+		if slices.Contains(locked, name) {
+			if !warnedLocked[name] {
+				warnedLocked[name] = true
+				fmt.Fprintf(os.Stderr, "Warning: %q is locked by the system config %s; ignoring it in %s\n", name, systemConfigPath(), userPath)
+			}
+			continue
+		}
+		merged[name] = mergeObjects(merged[name], raw)
+	}
+	return merged
+}
+
+// mergeObjects merges two JSON objects one level deep, over's entries
+// winning. Anything but two objects is simply replaced by over.
+func mergeObjects(base, over json.RawMessage) json.RawMessage {
+	var baseEntries, overEntries map[string]json.RawMessage
+	if json.Unmarshal(base, &baseEntries) != nil || json.Unmarshal(over, &overEntries) != nil ||
+		baseEntries == nil || overEntries == nil {
+		return over
+	}
+	for name, raw := range overEntries {
+		baseEntries[name] = raw
+	}
+	data, _ := json.Marshal(baseEntries)
+	return data
+}
+
+// checkUnlocked fails a change to the user's config that a locked field
+// would ignore
+func checkUnlocked(field string) error {
+	path := systemConfigPath()
+	system, err := readConfigFields(path)
+	if err != nil {
+		return err
+	}
+	locked, err := systemLocks(path, system)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(locked, field) {
+		return fmt.Errorf("%q is locked by the system config %s, so it can't be changed", field, path)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// prefixWriter writes a prefix at the start of every line
+type prefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	midLine bool
+}
+
+// Write copies p, inserting the prefix after each newline
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if !p.midLine {
+			if _, err := p.w.Write(p.prefix); err != nil {
+				return 0, err
+			}
+			p.midLine = true
+		}
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+			p.midLine = false
+		}
+		if _, err := p.w.Write(line); err != nil {
+			return 0, err
+		}
+		b = b[len(line):]
+	}
+	return n, nil
+}
+
+// flushTagged waits for tagged output to be written out; see tagOutput
+var flushTagged = func() {}
+
+// tagOutput prefixes every line seccli writes to *f with tag, by swapping
+// the file for a pipe. It covers fmt.Print calls and the VPN client's
+// output alike. The output must be flushed with flushTagged before exiting.
+func tagOutput(f **os.File, tag string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	orig := *f
+	*f = w
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&prefixWriter{w: orig, prefix: []byte(tag + " ")}, r)
+		close(done)
+	}()
+
+	previous := flushTagged
+	flushTagged = func() {
+		w.Close()
+		<-done
+		*f = orig
+		previous()
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// maxSessions bounds disconnect --all, in case a client keeps reporting a
+// session that a disconnect doesn't remove
+const maxSessions = 8
+
+// disconnectResult reports a successful disconnect
+type disconnectResult struct {
+	vpnStatus
+
+	// Routes is filled in by --route-changes
+	Routes *routeChanges `json:"routes,omitempty"`
+}
+
+// String formats the result for text output
+func (r disconnectResult) String() string {
+	text := "VPN disconnection successful"
+	if r.Routes != nil {
+		text += "\n" + r.Routes.String()
+	}
+	return text
+}
+
+// disconnectedSession is one session torn down by disconnect --all
+type disconnectedSession struct {
+	Server string `json:"server,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// disconnectAllResult reports the sessions disconnect --all found
+type disconnectAllResult struct {
+	vpnStatus
+	Sessions []disconnectedSession `json:"sessions"`
+	Routes   *routeChanges         `json:"routes,omitempty"`
+}
+
+// String formats the result for text output
+func (r disconnectAllResult) String() string {
+	if len(r.Sessions) == 0 {
+		return "No active VPN sessions"
+	}
+	var b strings.Builder
+	for i, session := range r.Sessions {
+		server := session.Server
+		if server == "" {
+			server = "(unknown server)"
+		}
+		if session.Error != "" {
+			fmt.Fprintf(&b, "Session %d (%s): failed: %s\n", i+1, server, session.Error)
+		} else {
+			fmt.Fprintf(&b, "Session %d (%s): disconnected\n", i+1, server)
+		}
+	}
+	b.WriteString(r.vpnStatus.String())
+	if r.Routes != nil {
+		b.WriteString("\n" + r.Routes.String())
+	}
+	return b.String()
+}
+
+// disconnectAll disconnects sessions until the client reports none. Cisco
+// clients hold a single session, so this usually takes one round, but it
+// also clears a session that another profile brought up behind it.
+func disconnectAll(vpnExec string, verbose, confirm bool, before func()) (disconnectAllResult, error) {
+	result := disconnectAllResult{vpnStatus: newVPNStatus(false), Sessions: []disconnectedSession{}}
+
+	for i := 0; i < maxSessions && vpnConnected(vpnExec); i++ {
+		var session disconnectedSession
+		if stats, err := getStats(vpnExec); err == nil {
+			session.Server = stats.Server
+		}
+		slog.Info("disconnecting session", "session", i+1, "server", session.Server)
+
+		err := disconnectVPN(vpnExec, verbose, confirm && i == 0, before)
+		if err != nil && i == 0 {
+			// Nothing was torn down yet, e.g. the confirmation was declined
+			return result, err
+		}
+		if err != nil {
+			session.Error = err.Error()
+			result.Sessions = append(result.Sessions, session)
+			result.Connected = vpnConnected(vpnExec)
+			return result, fmt.Errorf("failed to disconnect all sessions: %v", err)
+		}
+		result.Sessions = append(result.Sessions, session)
+	}
+	result.Connected = vpnConnected(vpnExec)
+	if result.Connected {
+		return result, fmt.Errorf("a VPN session is still active after %d disconnects", maxSessions)
+	}
+
+	// The client can't see tunnels left by a crashed or second client
+	if tunnels, err := findActiveTunnels(); err == nil {
+		for _, t := range tunnels {
+			if strings.HasPrefix(t.Tool, "Cisco") {
+				fmt.Fprintf(os.Stderr, "Warning: Cisco tunnel interface %s is still up\n", t.Name)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// exitTimeout is the exit code when --timeout-all expires, as with timeout(1)
+const exitTimeout = 124
+
+// timeoutGrace is how long the command gets to wind down after
+// --timeout-all expires before seccli exits regardless
+const timeoutGrace = 2 * time.Second
+
+// commandCtx bounds every child process. It is cancelled when --timeout-all
+// expires, which kills any child still running.
+var commandCtx = context.Background()
+
+// startTimeoutAll bounds the whole run to d. The returned context is
+// cancelled at the deadline; if the command still hasn't returned after a
+// grace period (say, blocked reading the password), seccli exits anyway.
+func startTimeoutAll(ctx context.Context, d time.Duration) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	commandCtx = ctx
+
+	// Put the terminal back if the hard stop interrupts a password prompt
+	var restore func()
+	if state, err := term.GetState(int(os.Stdin.Fd())); err == nil {
+		restore = func() { term.Restore(int(os.Stdin.Fd()), state) }
+	}
+
+	time.AfterFunc(d, func() {
+		cancel(fmt.Errorf("gave up after --timeout-all %s", d))
+		time.AfterFunc(timeoutGrace, func() {
+			if restore != nil {
+				restore()
+			}
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", context.Cause(ctx))
+			exit(exitTimeout)
+		})
+	})
+	return ctx
+}
+
+// timedOut returns the --timeout-all error if it has expired
+func timedOut() error {
+	if commandCtx.Err() == nil {
+		return nil
+	}
+	return context.Cause(commandCtx)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of connect --transport
+const (
+	transportAuto = "auto"
+	transportDTLS = "dtls"
+	transportTLS  = "tls"
+)
+
+// validateTransport checks a --transport value
+func validateTransport(transport string) error {
+	switch transport {
+	case transportAuto, transportDTLS, transportTLS:
+		return nil
+	}
+	return fmt.Errorf("invalid transport %q (expected auto, dtls or tls)", transport)
+}
+
+// transportOf names the transport behind a "vpn stats" protocol such as
+// "DTLSv1.2", or "" if it isn't recognizable
+func transportOf(protocol string) string {
+	upper := strings.ToUpper(protocol)
+	switch {
+	case strings.Contains(upper, "DTLS"):
+		return transportDTLS
+	case strings.Contains(upper, "TLS"):
+		return transportTLS
+	}
+	return ""
+}
+
+// checkTransport warns if the tunnel didn't come up over the wanted
+// transport. The client has no switch for it (the gateway and the network
+// decide), so it can only be checked after connecting.
+func checkTransport(want, got string) string {
+	switch {
+	case want == transportAuto || want == got:
+		return ""
+	case got == "":
+		return fmt.Sprintf("Warning: the client didn't report the transport, so the %s check was skipped", strings.ToUpper(want))
+	case want == transportDTLS:
+		return "Warning: connected over TLS only, not DTLS; UDP port 443 is probably blocked on this network, which costs throughput"
+	}
+	return "Warning: connected over DTLS, not TLS"
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// uiRefreshInterval is how often the dashboard re-reads the VPN state
+const uiRefreshInterval = 2 * time.Second
+
+// Keys the dashboard treats specially in raw mode
+const (
+	keyCtrlC     = 3
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// dashboard is the state of the ui command's screen
+type dashboard struct {
+	vpnExec string
+	opts    connectOptions
+	target  error // why connecting is unavailable, if it is
+
+	fd       int
+	rawState *term.State
+	keys     chan byte
+
+	connected bool
+	stats     vpnStats
+	message   string
+}
+
+// uiAction handles the ui command
+func uiAction(ctx context.Context, cmd *cli.Command) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("the ui command needs an interactive terminal")
+	}
+
+	vpnExec, err := getVPNExec(cmd)
+	if err != nil {
+		return err
+	}
+
+	d := &dashboard{vpnExec: vpnExec, fd: fd, keys: make(chan byte)}
+
+	// The dashboard is still useful for watching and disconnecting when
+	// there is nothing to connect to, so only report the problem
+	host, username, method, err := resolveTarget(cmd)
+	d.target = err
+	d.opts = connectOptions{
+		Host:           host,
+		Username:       username,
+		Method:         method,
+		ConnectTimeout: 30 * time.Second,
+		NoPrompt:       true,
+	}
+
+	if err := d.enterRaw(); err != nil {
+		return err
+	}
+	defer d.leaveRaw()
+
+	// A single reader owns stdin, so the password prompt reads keys from
+	// the same channel instead of racing it
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				close(d.keys)
+				return
+			}
+			d.keys <- buf[0]
+		}
+	}()
+
+	ticker := time.NewTicker(uiRefreshInterval)
+	defer ticker.Stop()
+
+	d.refresh()
+	for {
+		d.draw()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.refresh()
+		case key, ok := <-d.keys:
+			if !ok {
+				return nil
+			}
+			switch key {
+			case 'q', keyCtrlC:
+				d.clear()
+				return nil
+			case 'c':
+				d.connect(ctx)
+			case 'd':
+				d.disconnect()
+			case 'r':
+				if d.disconnect() {
+					d.connect(ctx)
+				}
+			}
+			d.refresh()
+		}
+	}
+}
+
+// refresh re-reads the connection state and statistics. The state comes
+// from the network interfaces while the last session's address is still
+// up, so polling doesn't run "vpn status" every time.
+func (d *dashboard) refresh() {
+	d.connected = pollState(d.vpnExec, d.stats.ClientAddress) == stateConnected
+	d.stats = vpnStats{}
+	if d.connected {
+		if stats, err := getStats(d.vpnExec); err == nil {
+			d.stats = stats
+		}
+	}
+}
+
+// draw repaints the whole screen. Raw mode needs explicit carriage returns.
+func (d *dashboard) draw() {
+	orDash := func(v string) string {
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+
+	var lines []string
+	lines = append(lines, "seccli dashboard", "")
+	if d.connected {
+		lines = append(lines,
+			"Status:    Connected",
+			"Server:    "+orDash(d.stats.Server),
+			"Address:   "+orDash(d.stats.ClientAddress),
+			"Uptime:    "+orDash(d.stats.Duration),
+			fmt.Sprintf("Sent:      %d bytes", d.stats.BytesSent),
+			fmt.Sprintf("Received:  %d bytes", d.stats.BytesReceived),
+		)
+	} else {
+		lines = append(lines, "Status:    Disconnected")
+	}
+	if d.target == nil {
+		lines = append(lines, "", fmt.Sprintf("Target:    %s@%s", d.opts.Username, d.opts.Host))
+	} else {
+		lines = append(lines, "", fmt.Sprintf("Connect unavailable: %v", d.target))
+	}
+	if d.message != "" {
+		lines = append(lines, "", d.message)
+	}
+	lines = append(lines, "", "[c] connect  [d] disconnect  [r] reconnect  [q] quit")
+
+	d.clear()
+	fmt.Print(strings.ReplaceAll(strings.Join(lines, "\n"), "\n", "\r\n"))
+}
+
+// clear blanks the screen and homes the cursor
+func (d *dashboard) clear() {
+	fmt.Print("\x1b[H\x1b[2J")
+}
+
+// connect asks for the password and connects, reporting the outcome
+func (d *dashboard) connect(ctx context.Context) {
+	if d.target != nil {
+		d.message = fmt.Sprintf("Can't connect: %v", d.target)
+		return
+	}
+	if d.connected {
+		d.message = "Already connected"
+		return
+	}
+
+	// An empty password would make connectVPN prompt on stdin itself
+	password, ok := d.readPassword()
+	if !ok || password == "" {
+		d.message = "Connect cancelled"
+		return
+	}
+
+	opts := d.opts
+	opts.Password = password
+	var err error
+	d.cooked(func() {
+		_, err = connectVPN(ctx, d.vpnExec, opts)
+	})
+	if err != nil {
+		saveLastError(err)
+		d.message = fmt.Sprintf("Connect failed: %v", err)
+		return
+	}
+	clearLastError()
+	d.message = "Connected"
+}
+
+// disconnect tears down the tunnel, reporting the outcome
+func (d *dashboard) disconnect() bool {
+	if !d.connected {
+		d.message = "Not connected"
+		return true
+	}
+	var err error
+	d.cooked(func() {
+		err = disconnectVPN(d.vpnExec, false, false, nil)
+	})
+	if err != nil {
+		d.message = fmt.Sprintf("Disconnect failed: %v", err)
+		return false
+	}
+	d.connected = false
+	d.message = "Disconnected"
+	return true
+}
+
+// readPassword reads a line from the key channel without echoing it.
+// Escape or Ctrl-C cancels.
+func (d *dashboard) readPassword() (string, bool) {
+	d.clear()
+	fmt.Printf("Connecting to %s as %s\r\n\r\nEnter VPN password (Esc to cancel): ", d.opts.Host, d.opts.Username)
+
+	var password []byte
+	for key := range d.keys {
+		switch key {
+		case '\r', '\n':
+			return string(password), true
+		case keyEscape, keyCtrlC:
+			return "", false
+		case keyBackspace, '\b':
+			if len(password) > 0 {
+				password = password[:len(password)-1]
+			}
+		default:
+			password = append(password, key)
+		}
+	}
+	return "", false
+}
+
+// cooked runs f with the terminal back in normal mode, so spinners and
+// client output render as they do outside the dashboard
+func (d *dashboard) cooked(f func()) {
+	d.clear()
+	d.leaveRaw()
+	defer d.enterRaw()
+	f()
+}
+
+// enterRaw switches the terminal to raw mode for single-key input
+func (d *dashboard) enterRaw() error {
+	state, err := term.MakeRaw(d.fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %v", err)
+	}
+	d.rawState = state
+	fmt.Print("\x1b[?25l") // hide the cursor
+	return nil
+}
+
+// leaveRaw restores the terminal to how it was before enterRaw
+func (d *dashboard) leaveRaw() {
+	if d.rawState == nil {
+		return
+	}
+	fmt.Print("\x1b[?25h")
+	term.Restore(d.fd, d.rawState)
+	d.rawState = nil
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// dashboardHeapSlack is how much the heap may grow over the poll cycles
+// of TestDashboardBounded, for noise like the runtime's own caches
+const dashboardHeapSlack = 256 << 10
+
+// TestDashboardBounded runs the dashboard's refresh and its connect and
+// disconnect keys against the stub client many times over and checks that
+// neither goroutines nor the heap grow, as in a long-running ui they would
+// pile up
+func TestDashboardBounded(t *testing.T) {
+	e := newStubEnv(t)
+	e.setenv(t)
+	master, slave := openPTY(t)
+	defer master.Close()
+	defer slave.Close()
+	discardOutput(t)
+
+	d := &dashboard{
+		vpnExec: e.vpn,
+		fd:      int(slave.Fd()),
+		keys:    make(chan byte, 64),
+		opts: connectOptions{
+			Host:           "vpn.example.edu",
+			Username:       "me",
+			Method:         "push",
+			ConnectTimeout: 5 * time.Second,
+			NoPrompt:       true,
+		},
+	}
+	cycle := func() {
+		for _, key := range []byte("password\r") {
+			d.keys <- key
+		}
+		d.connect(context.Background())
+		if d.message != "Connected" {
+			t.Fatalf("connect: %s", d.message)
+		}
+		for range 10 {
+			d.refresh()
+			d.draw()
+		}
+		if !d.connected {
+			t.Fatal("not connected after connecting")
+		}
+		if !d.disconnect() {
+			t.Fatalf("disconnect: %s", d.message)
+		}
+		d.refresh()
+		d.draw()
+		if d.connected {
+			t.Fatal("connected after disconnecting")
+		}
+	}
+
+	cycle()
+	before, heapBefore := runtime.NumGoroutine(), heapInUse()
+	for range 20 {
+		cycle()
+	}
+	if after := settledGoroutines(before); after > before {
+		t.Errorf("goroutines grew from %d to %d over 20 cycles", before, after)
+	}
+	if heapAfter := heapInUse(); heapAfter > heapBefore+dashboardHeapSlack {
+		t.Errorf("heap in use grew from %d to %d bytes over 20 cycles", heapBefore, heapAfter)
+	}
+}
+
+// heapInUse returns the live heap after a collection
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// discardOutput sends the process's stdout, stderr and logs, which the
+// dashboard and its spinners draw on, to /dev/null for the rest of the test
+func discardOutput(t *testing.T) {
+	t.Helper()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, logger := os.Stdout, os.Stderr, slog.Default()
+	os.Stdout, os.Stderr = null, null
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		slog.SetDefault(logger)
+		null.Close()
+	})
+}
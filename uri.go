@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// connectURI holds the connection parameters from a vpn:// URI
+type connectURI struct {
+	Host     string
+	Username string
+	Method   string
+}
+
+// parseConnectURI parses a URI like vpn://host?user=netid&method=push
+func parseConnectURI(raw string) (connectURI, error) {
+	var result connectURI
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return result, fmt.Errorf("invalid --uri: %v", err)
+	}
+	if u.Scheme != "vpn" {
+		return result, fmt.Errorf("invalid --uri %q: scheme must be vpn://", raw)
+	}
+	if u.Host == "" {
+		return result, fmt.Errorf("invalid --uri %q: missing host", raw)
+	}
+	if u.User != nil {
+		return result, fmt.Errorf("invalid --uri %q: credentials are not allowed in the URI; use ?user=", raw)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return result, fmt.Errorf("invalid --uri %q: unexpected path %q", raw, u.Path)
+	}
+	if u.Fragment != "" {
+		return result, fmt.Errorf("invalid --uri %q: unexpected fragment", raw)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return result, fmt.Errorf("invalid --uri %q: %v", raw, err)
+	}
+	for key, values := range query {
+		if len(values) != 1 {
+			return result, fmt.Errorf("invalid --uri %q: %s given more than once", raw, key)
+		}
+		value := strings.TrimSpace(values[0])
+		switch key {
+		case "user":
+			result.Username = value
+		case "method":
+			result.Method = value
+		default:
+			return result, fmt.Errorf("invalid --uri %q: unknown parameter %q", raw, key)
+		}
+	}
+
+	result.Host = u.Host
+	return result, nil
+}
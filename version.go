@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Build details, set by release builds with
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// versionInfo describes the running binary
+type versionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+	Go      string `json:"go"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+}
+
+// String formats the version for text output
+func (v versionInfo) String() string {
+	s := "seccli " + v.Version
+	if v.Commit != "" {
+		s += " (" + v.Commit
+		if v.Date != "" {
+			s += ", " + v.Date
+		}
+		s += ")"
+	}
+	return s + fmt.Sprintf("\n%s %s/%s", v.Go, v.OS, v.Arch)
+}
+
+// currentVersion returns the build details. Without -ldflags, as with
+// "go install" or a plain "go build", it falls back to what the Go
+// toolchain recorded about the module and the VCS checkout.
+func currentVersion() versionInfo {
+	v := versionInfo{Version: version, Commit: commit, Date: date, Go: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	if v.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		v.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if v.Commit == "" {
+				v.Commit = setting.Value
+			}
+		case "vcs.time":
+			if v.Date == "" {
+				v.Date = setting.Value
+			}
+		}
+	}
+	return v
+}
+
+// versionAction handles the version command
+func versionAction(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("output")
+	if cmd.Bool("json") {
+		format = formatJSON
+	}
+	return render(currentVersion(), format)
+}
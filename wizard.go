@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// defaultInitHost is suggested by config init
+const defaultInitHost = "cuvpn.cuvpn.cornell.edu"
+
+// wizard asks config init's questions. All answers come through one reader,
+// so input piped in ahead of time isn't lost between prompts.
+type wizard struct {
+	in *bufio.Reader
+}
+
+// ask prompts for a value, returning def if the answer is empty
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := w.in.ReadString('\n')
+	if err != nil && (answer == "" || err != io.EOF) {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// confirm asks a yes/no question, defaulting to no
+func (w *wizard) confirm(question string) (bool, error) {
+	answer, err := w.ask(question+" [y/N]", "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// configInitAction writes a first config with one profile, asking for
+// whatever the flags don't give. With --non-interactive (or without a
+// terminal) nothing is asked and --vpn-host is required.
+func configInitAction(ctx context.Context, cmd *cli.Command) error {
+	interactive := !cmd.Bool("non-interactive") && term.IsTerminal(int(os.Stdin.Fd()))
+	w := &wizard{in: bufio.NewReader(os.Stdin)}
+
+	configPath := cmd.String("config")
+	if err := checkUnlocked("profiles"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); err == nil && !cmd.Bool("force") {
+		if !interactive {
+			return fmt.Errorf("%s already exists; use --force to update it", configPath)
+		}
+		ok, err := w.confirm(fmt.Sprintf("%s already exists. Update it?", configPath))
+		if err != nil {
+			return fmt.Errorf("failed to read answer: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("config init cancelled")
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check config: %v", err)
+	}
+
+	// Flags answer their question; the rest are asked, or defaulted
+	answer := func(flag, question, def string) (string, error) {
+		if cmd.IsSet(flag) {
+			return cmd.String(flag), nil
+		}
+		if !interactive {
+			return def, nil
+		}
+		value, err := w.ask(question, def)
+		if err != nil {
+			return "", fmt.Errorf("failed to read answer: %v", err)
+		}
+		return value, nil
+	}
+
+	name, err := answer("name", "Profile name", "cornell")
+	if err != nil {
+		return err
+	}
+	hostDefault := defaultInitHost
+	if !interactive {
+		hostDefault = ""
+	}
+	host, err := answer("vpn-host", "VPN host", hostDefault)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("config init needs --vpn-host when not run interactively")
+	}
+	username, err := answer("username", "Username (NetID)", "")
+	if err != nil {
+		return err
+	}
+	method, err := answer("method", "Duo method (push, phone, sms)", "push")
+	if err != nil {
+		return err
+	}
+	if isPasscode(method) {
+		return fmt.Errorf("a one-time passcode can't be saved as a method; use push, phone or sms")
+	}
+
+	p := profile{Host: host, Username: username, Method: method}
+	if err := validateProfile(name, p); err != nil {
+		return err
+	}
+
+	merged, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadUserConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	vpnExec := cmd.String("vpn-exec")
+	if vpnExec == "" && !cmd.Bool("no-detect") {
+		found, err := findVPNExec("", extraExecPaths(merged))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; pass --vpn-exec to connect, or rerun config init once the client is installed\n", err)
+		} else {
+			fmt.Printf("Found VPN client at %s\n", found)
+			vpnExec = found
+		}
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	cfg.Profiles[name] = p
+	if vpnExec != "" && checkUnlocked("vpn_exec") == nil {
+		cfg.VPNExec = vpnExec
+	}
+	if err := saveConfig(configPath, cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s. Connect with:\n  seccli connect --profile %s\n", configPath, name)
+	return nil
+}